@@ -0,0 +1,283 @@
+// Package client is a typed Go client for the library API. It owns the wire
+// contract so the CLI and other services don't hand-roll HTTP calls.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kushalpraja/library-api/models"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultRetries    = 2
+	defaultRetryDelay = 200 * time.Millisecond
+)
+
+// Client talks to a library API server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	token      string
+	userAgent  string
+	retries    int
+	retryDelay time.Duration
+	// idempotencyKeys makes POST requests carry a generated Idempotency-Key
+	idempotencyKeys bool
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout sets the overall timeout of each HTTP attempt
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = d }
+}
+
+// WithAPIKey sends key in the X-API-Key header
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBearerToken sends token in the Authorization header
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithRetries sets how many times idempotent requests, including POSTs sent
+// with an Idempotency-Key, are retried after a transport error or a
+// 429/502/503/504, and the delay before the first retry.
+// The delay doubles on every further attempt.
+func WithRetries(n int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.retryDelay = delay
+	}
+}
+
+// WithIdempotencyKeys turns the automatic Idempotency-Key on POST requests
+// on or off; it is on by default. With a key, POSTs are retried like
+// idempotent requests since the server replays the first response instead
+// of applying the request twice.
+func WithIdempotencyKeys(enabled bool) Option {
+	return func(c *Client) { c.idempotencyKeys = enabled }
+}
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey makes the POST request sent with ctx use key instead
+// of a generated one, so a caller can keep the key across restarts
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// NewIdempotencyKey returns a random key suitable for WithIdempotencyKey
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// New creates a client for the API at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "library-api-client",
+		retries:    defaultRetries,
+		retryDelay: defaultRetryDelay,
+
+		idempotencyKeys: true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the API address the client was created with
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// ListBooks returns every book in the catalogue
+func (c *Client) ListBooks(ctx context.Context) ([]models.Book, error) {
+	var books []models.Book
+	err := c.do(ctx, http.MethodGet, "/v1/books/list", nil, &books)
+	return books, err
+}
+
+// GetBook returns the book with the given id
+func (c *Client) GetBook(ctx context.Context, id int64) (models.Book, error) {
+	var book models.Book
+	err := c.do(ctx, http.MethodGet, "/v1/books/"+strconv.FormatInt(id, 10), nil, &book)
+	return book, err
+}
+
+// SearchBooks returns books whose title or author contains query, or whose ISBN or year equals it
+func (c *Client) SearchBooks(ctx context.Context, query string) ([]models.Book, error) {
+	var books []models.Book
+	err := c.do(ctx, http.MethodGet, "/v1/books/search?q="+url.QueryEscape(query), nil, &books)
+	return books, err
+}
+
+// AddBook creates a book
+func (c *Client) AddBook(ctx context.Context, req models.CreateBookRequest) error {
+	return c.do(ctx, http.MethodPost, "/v1/books/add", req, nil)
+}
+
+// UpdateBook changes the fields set in req and returns the updated book
+func (c *Client) UpdateBook(ctx context.Context, id int64, req models.UpdateBookRequest) (models.Book, error) {
+	var book models.Book
+	err := c.do(ctx, http.MethodPatch, "/v1/books/"+strconv.FormatInt(id, 10), req, &book)
+	return book, err
+}
+
+// EditBook changes one field of the books with the given title
+func (c *Client) EditBook(ctx context.Context, req models.EditRequest) error {
+	return c.do(ctx, http.MethodPatch, "/v1/books/edit", req, nil)
+}
+
+// DeleteBook deletes the books with the given title
+func (c *Client) DeleteBook(ctx context.Context, title string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/books/delete", models.DeleteRequest{Title: title}, nil)
+}
+
+// DeleteBookByID deletes a single book
+func (c *Client) DeleteBookByID(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, "/v1/books/"+strconv.FormatInt(id, 10), nil, nil)
+}
+
+// Batch applies a list of create, update and delete operations in one
+// transaction. If the batch is rolled back the error names the failing
+// operation.
+func (c *Client) Batch(ctx context.Context, req models.BatchRequest) (models.BatchResponse, error) {
+	var resp models.BatchResponse
+	err := c.do(ctx, http.MethodPost, "/v1/books/batch", req, &resp)
+	return resp, err
+}
+
+// LookupISBN fetches prefilled book fields for an ISBN from the server's metadata provider
+func (c *Client) LookupISBN(ctx context.Context, isbn string) (models.LookupResponse, error) {
+	var resp models.LookupResponse
+	err := c.do(ctx, http.MethodPost, "/v1/books/lookup", models.LookupRequest{ISBN: isbn}, &resp)
+	return resp, err
+}
+
+// do sends body as JSON and decodes a successful response into out,
+// retrying idempotent requests on transient failures
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	// one key for every attempt, so the server can spot the retries
+	idempotencyKey, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	if method == http.MethodPost && idempotencyKey == "" && c.idempotencyKeys {
+		idempotencyKey = NewIdempotencyKey()
+	}
+
+	attempts := 1
+	if idempotent(method) || idempotencyKey != "" {
+		attempts += c.retries
+	}
+
+	var err error
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		err = c.attempt(ctx, method, path, idempotencyKey, payload, out)
+		if err == nil || attempt >= attempts || !retryable(err) {
+			return err
+		}
+
+		// wait at least as long as a rate-limited server asked
+		wait := delay
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, path, idempotencyKey string, payload []byte, out any) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &TransportError{Method: method, Path: path, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return newAPIError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
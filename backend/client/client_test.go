@@ -0,0 +1,402 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/kushalpraja/library-api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reply is one scripted response
+type reply struct {
+	status int
+	body   string
+	header map[string]string
+}
+
+// server answers with replies in turn, repeating the last one, and keeps
+// the requests it was sent
+type server struct {
+	*httptest.Server
+	replies []reply
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func newServer(t *testing.T, replies ...reply) *server {
+	t.Helper()
+	s := &server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+
+		rep := s.replies[min(n, len(s.replies)-1)]
+		for k, v := range rep.header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rep.status)
+		io.WriteString(w, rep.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func (s *server) header(i int, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[i].Header.Get(name)
+}
+
+// fast retries so the tests don't wait
+func newClient(s *server, opts ...Option) *Client {
+	return New(s.URL, append([]Option{WithRetries(2, time.Millisecond)}, opts...)...)
+}
+
+var (
+	ok          = reply{status: 200, body: `[]`}
+	unavailable = reply{status: 503, body: `{"error": "Server is shutting down"}`}
+)
+
+func TestRetryPolicy(t *testing.T) {
+	book := models.CreateBookRequest{BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}
+	for _, tc := range []struct {
+		name    string
+		replies []reply
+		opts    []Option
+		call    func(*Client) error
+		calls   int
+		wantErr int
+	}{
+		{
+			name:    "GET retried until it succeeds",
+			replies: []reply{unavailable, {status: 502}, ok},
+			call:    func(c *Client) error { _, err := c.ListBooks(context.Background()); return err },
+			calls:   3,
+		},
+		{
+			name:    "GET gives up after the retries",
+			replies: []reply{unavailable},
+			call:    func(c *Client) error { _, err := c.ListBooks(context.Background()); return err },
+			calls:   3,
+			wantErr: 503,
+		},
+		{
+			name:    "DELETE retried",
+			replies: []reply{{status: 504}, ok},
+			call:    func(c *Client) error { return c.DeleteBookByID(context.Background(), 1) },
+			calls:   2,
+		},
+		{
+			name:    "500 not retried",
+			replies: []reply{{status: 500, body: `{"error": "Internal server error"}`}, ok},
+			call:    func(c *Client) error { _, err := c.ListBooks(context.Background()); return err },
+			calls:   1,
+			wantErr: 500,
+		},
+		{
+			name:    "400 not retried",
+			replies: []reply{{status: 400, body: `{"error": "Invalid request"}`}, ok},
+			call:    func(c *Client) error { _, err := c.ListBooks(context.Background()); return err },
+			calls:   1,
+			wantErr: 400,
+		},
+		{
+			name:    "POST with an Idempotency-Key retried",
+			replies: []reply{unavailable, ok},
+			call:    func(c *Client) error { return c.AddBook(context.Background(), book) },
+			calls:   2,
+		},
+		{
+			name:    "POST without an Idempotency-Key not retried",
+			replies: []reply{unavailable, ok},
+			opts:    []Option{WithIdempotencyKeys(false)},
+			call:    func(c *Client) error { return c.AddBook(context.Background(), book) },
+			calls:   1,
+			wantErr: 503,
+		},
+		{
+			name:    "PATCH not retried",
+			replies: []reply{unavailable, ok},
+			call: func(c *Client) error {
+				_, err := c.UpdateBook(context.Background(), 1, models.UpdateBookRequest{})
+				return err
+			},
+			calls:   1,
+			wantErr: 503,
+		},
+		{
+			name:    "POST retried while the first attempt is in progress",
+			replies: []reply{{status: 409, body: `{"error": "in progress", "code": "idempotency_in_progress"}`}, ok},
+			call:    func(c *Client) error { return c.AddBook(context.Background(), book) },
+			calls:   2,
+		},
+		{
+			name:    "POST not retried on a key reused with another body",
+			replies: []reply{{status: 422, body: `{"error": "mismatch", "code": "idempotency_key_reused"}`}, ok},
+			call:    func(c *Client) error { return c.AddBook(context.Background(), book) },
+			calls:   1,
+			wantErr: 422,
+		},
+		{
+			name:    "no retries when disabled",
+			replies: []reply{unavailable, ok},
+			opts:    []Option{WithRetries(0, time.Millisecond)},
+			call:    func(c *Client) error { _, err := c.ListBooks(context.Background()); return err },
+			calls:   1,
+			wantErr: 503,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t, tc.replies...)
+			err := tc.call(newClient(s, tc.opts...))
+			if s.calls() != tc.calls {
+				t.Errorf("sent %d requests, want %d", s.calls(), tc.calls)
+			}
+			var apiErr *APIError
+			switch {
+			case tc.wantErr == 0 && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tc.wantErr != 0 && !errors.As(err, &apiErr):
+				t.Errorf("error %v, want an APIError", err)
+			case tc.wantErr != 0 && apiErr.StatusCode != tc.wantErr:
+				t.Errorf("status %d, want %d", apiErr.StatusCode, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyReusedAcrossAttempts(t *testing.T) {
+	s := newServer(t, unavailable, unavailable, ok)
+	c := newClient(s)
+	if err := c.AddBook(context.Background(), models.CreateBookRequest{BookName: "Dune"}); err != nil {
+		t.Fatal(err)
+	}
+	key := s.header(0, "Idempotency-Key")
+	if len(key) != 32 {
+		t.Fatalf("Idempotency-Key %q, want 32 hex digits", key)
+	}
+	for i := 1; i < s.calls(); i++ {
+		if got := s.header(i, "Idempotency-Key"); got != key {
+			t.Errorf("attempt %d sent key %q, the first sent %q", i+1, got, key)
+		}
+	}
+	if s.bodies[0] != s.bodies[2] {
+		t.Errorf("retry sent body %s, the first sent %s", s.bodies[2], s.bodies[0])
+	}
+
+	// every request gets its own key
+	if err := c.AddBook(context.Background(), models.CreateBookRequest{BookName: "Dune"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.header(3, "Idempotency-Key"); got == key || got == "" {
+		t.Errorf("second request sent key %q after %q", got, key)
+	}
+
+	// unless the caller picks one
+	ctx := WithIdempotencyKey(context.Background(), "order-42")
+	if err := c.AddBook(ctx, models.CreateBookRequest{BookName: "Dune"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.header(4, "Idempotency-Key"); got != "order-42" {
+		t.Errorf("Idempotency-Key %q, want the caller's", got)
+	}
+
+	// and only POSTs carry one
+	if _, err := c.ListBooks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.header(5, "Idempotency-Key"); got != "" {
+		t.Errorf("GET sent Idempotency-Key %q", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := newServer(t, reply{status: 429, body: `{"error": "Too many requests"}`, header: map[string]string{"Retry-After": "1"}}, ok)
+	start := time.Now()
+	if _, err := newClient(s).ListBooks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, the server asked for 1s", elapsed)
+	}
+	if s.calls() != 2 {
+		t.Errorf("sent %d requests, want 2", s.calls())
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	s := newServer(t, unavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := New(s.URL, WithRetries(5, time.Hour)).ListBooks(ctx)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 503 {
+		t.Fatalf("error %v, want the last 503", err)
+	}
+	if s.calls() != 1 {
+		t.Errorf("sent %d requests, want 1", s.calls())
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		reply reply
+		want  APIError
+	}{
+		{
+			name:  "error body",
+			reply: reply{status: 404, body: `{"error": "Book not found", "code": "not_found"}`},
+			want:  APIError{StatusCode: 404, Message: "Book not found", Code: "not_found"},
+		},
+		{
+			name: "validation fields",
+			reply: reply{status: 400, body: `{"error": "Invalid request: isbn is not valid", "code": "validation_failed",
+				"fields": {"isbn": "is not valid"}}`},
+			want: APIError{StatusCode: 400, Message: "Invalid request: isbn is not valid", Code: "validation_failed",
+				Fields: map[string]string{"isbn": "is not valid"}},
+		},
+		{
+			name:  "body that isn't an error",
+			reply: reply{status: 502, body: `<html>Bad Gateway</html>`},
+			want:  APIError{StatusCode: 502, Message: "Bad Gateway"},
+		},
+		{
+			name:  "Retry-After",
+			reply: reply{status: 503, body: `{"error": "Try later"}`, header: map[string]string{"Retry-After": "30"}},
+			want:  APIError{StatusCode: 503, Message: "Try later", RetryAfter: 30 * time.Second},
+		},
+		{
+			name:  "Retry-After as a date is ignored",
+			reply: reply{status: 503, body: `{"error": "Try later"}`, header: map[string]string{"Retry-After": "Fri, 30 Apr 2027 00:00:00 GMT"}},
+			want:  APIError{StatusCode: 503, Message: "Try later"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t, tc.reply)
+			_, err := New(s.URL, WithRetries(0, 0)).GetBook(context.Background(), 1)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error %v, want an APIError", err)
+			}
+			if !reflect.DeepEqual(*apiErr, tc.want) {
+				t.Errorf("got %+v, want %+v", *apiErr, tc.want)
+			}
+			if got := errors.Is(err, ErrNotFound); got != (tc.want.StatusCode == 404) {
+				t.Errorf("errors.Is(err, ErrNotFound) = %v", got)
+			}
+			if errors.Is(err, ErrUnavailable) {
+				t.Error("an answer from the server matches ErrUnavailable")
+			}
+		})
+	}
+}
+
+// failingTransport fails every request without sending it
+type failingTransport struct{ calls int }
+
+func (f *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	f.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestTransportError(t *testing.T) {
+	transport := &failingTransport{}
+	c := New("http://library.invalid", WithHTTPClient(&http.Client{Transport: transport}), WithRetries(2, time.Millisecond))
+	_, err := c.GetBook(context.Background(), 7)
+
+	var tErr *TransportError
+	if !errors.As(err, &tErr) {
+		t.Fatalf("error %v, want a TransportError", err)
+	}
+	if tErr.Method != "GET" || tErr.Path != "/v1/books/7" {
+		t.Errorf("TransportError for %s %s", tErr.Method, tErr.Path)
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("transport failure doesn't match ErrUnavailable")
+	}
+	if transport.calls != 3 {
+		t.Errorf("made %d attempts, want 3", transport.calls)
+	}
+
+	// a canceled request isn't the server being unavailable, nor retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := newServer(t, ok)
+	_, err = newClient(s).ListBooks(ctx)
+	if !errors.As(err, &tErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want a TransportError wrapping context.Canceled", err)
+	}
+	if errors.Is(err, ErrUnavailable) {
+		t.Error("canceled request matches ErrUnavailable")
+	}
+	if s.calls() != 0 {
+		t.Errorf("sent %d requests with a canceled context", s.calls())
+	}
+}
+
+func TestRequest(t *testing.T) {
+	s := newServer(t, reply{status: 200, body: `{"id": 7, "book_name": "Dune", "author": "Frank Herbert", "isbn": 9780441172719, "year": 1965}`})
+	c := newClient(s, WithAPIKey("key-1"), WithBearerToken("token-1"), WithUserAgent("library-cli/1.0"))
+	author := "Frank Herbert"
+	book, err := c.UpdateBook(context.Background(), 7, models.UpdateBookRequest{Author: &author})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.ID != 7 || book.BookName != "Dune" || book.Year != 1965 {
+		t.Errorf("decoded %+v", book)
+	}
+
+	r := s.requests[0]
+	if r.Method != "PATCH" || r.URL.Path != "/v1/books/7" {
+		t.Errorf("sent %s %s", r.Method, r.URL.Path)
+	}
+	if s.bodies[0] != `{"author":"Frank Herbert"}` {
+		t.Errorf("sent body %s", s.bodies[0])
+	}
+	for name, want := range map[string]string{
+		"Accept":        "application/json",
+		"Content-Type":  "application/json",
+		"User-Agent":    "library-cli/1.0",
+		"X-API-Key":     "key-1",
+		"Authorization": "Bearer token-1",
+	} {
+		if got := r.Header.Get(name); got != want {
+			t.Errorf("%s %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestUndecodableResponse(t *testing.T) {
+	s := newServer(t, reply{status: 200, body: `[{"id": "seven"}]`})
+	_, err := newClient(s).ListBooks(context.Background())
+	if err == nil {
+		t.Fatal("decoded a book with a string id")
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("decoding failure reported as %v", err)
+	}
+	if s.calls() != 1 {
+		t.Errorf("sent %d requests, want 1", s.calls())
+	}
+}
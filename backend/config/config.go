@@ -0,0 +1,226 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the server settings read from the environment at startup
+type Config struct {
+	MetadataBaseURL   string
+	MetadataTimeout   time.Duration
+	MetadataCacheTTL  time.Duration
+	MetadataCacheSize int
+	PublicBaseURL     string
+	LogLevel          string
+	LogFormat         string
+	ReadinessTimeout  time.Duration
+	QueryTimeout      time.Duration
+	IdempotencyTTL    time.Duration
+	// IdempotencyLease is how long a key stays claimed by a request that
+	// never finished, e.g. because the server crashed. It should be longer
+	// than any request runs, see WriteTimeout.
+	IdempotencyLease time.Duration
+	// RateLimits is a comma-separated list of "route=rate/unit:burst"
+	// entries, see ParseRateLimits
+	RateLimits string
+	// RateLimitKeysPerIP caps what one IP may send with API keys, in
+	// multiples of the limit of a single key
+	RateLimitKeysPerIP int
+	MaxBodyBytes       int
+	// TrustedProxies are the addresses or CIDR ranges of the reverse
+	// proxies whose X-Forwarded-For header is believed. With none the
+	// client IP is always the address of the connection.
+	TrustedProxies []string
+	// LegacyDeprecated is when the unversioned aliases of the /v1 routes
+	// were deprecated, the release that introduced /v1
+	LegacyDeprecated time.Time
+	// LegacySunset is when the unversioned aliases of the /v1 routes stop
+	// being served, announced in their Sunset header
+	LegacySunset time.Time
+	// GraphQLMaxDepth and GraphQLMaxComplexity bound the queries /graphql
+	// runs, see gql.MaxDepth and gql.MaxComplexity
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	CORS CORS
+
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownDelay keeps serving after /readyz starts failing so load
+	// balancers notice before connections are refused
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+
+	// GRPCAddr is where LibraryService listens, empty to not serve gRPC
+	GRPCAddr string
+
+	// EventsRetention is how long change events are kept for the event
+	// stream. Events a webhook hasn't been sent yet are kept until it has.
+	EventsRetention time.Duration
+	Webhooks        Webhooks
+}
+
+// Webhooks controls the delivery of change events to registered webhooks
+type Webhooks struct {
+	// Interval is how often pending events are looked for
+	Interval time.Duration
+	Timeout  time.Duration
+	// MaxAttempts is how often an event is tried before it is skipped
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// each further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// AllowPrivate lets webhooks target loopback, private and link-local
+	// addresses, which are refused by default
+	AllowPrivate bool
+}
+
+// CORS controls which browser origins may call the API. No origins means
+// cross-origin requests get no CORS headers and browsers block them.
+type CORS struct {
+	// AllowedOrigins are exact origins such as https://library.example.com,
+	// wildcard subdomains such as https://*.example.com, or * for any
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Load reads the configuration from environment variables, falling back to defaults
+func Load() Config {
+	return Config{
+		MetadataBaseURL:    getString("METADATA_BASE_URL", "https://openlibrary.org"),
+		MetadataTimeout:    getDuration("METADATA_TIMEOUT", 5*time.Second),
+		MetadataCacheTTL:   getDuration("METADATA_CACHE_TTL", 24*time.Hour),
+		MetadataCacheSize:  getInt("METADATA_CACHE_SIZE", 10000),
+		PublicBaseURL:      getString("PUBLIC_BASE_URL", ""),
+		LogLevel:           getString("LOG_LEVEL", "info"),
+		LogFormat:          getString("LOG_FORMAT", "json"),
+		ReadinessTimeout:   getDuration("READINESS_TIMEOUT", 2*time.Second),
+		QueryTimeout:       getDuration("QUERY_TIMEOUT", 5*time.Second),
+		IdempotencyTTL:     getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease:   getDuration("IDEMPOTENCY_LEASE", time.Minute),
+		RateLimits:         getString("RATE_LIMITS", DefaultRateLimits),
+		RateLimitKeysPerIP: getInt("RATE_LIMIT_KEYS_PER_IP", 4),
+		MaxBodyBytes:       getInt("MAX_BODY_BYTES", 1<<20),
+		TrustedProxies:     getList("TRUSTED_PROXIES", nil),
+		LegacyDeprecated:   getDate("LEGACY_ROUTES_DEPRECATED", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)),
+		LegacySunset:       getDate("LEGACY_ROUTES_SUNSET", time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)),
+
+		GraphQLMaxDepth:      getInt("GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity: getInt("GRAPHQL_MAX_COMPLEXITY", 1000),
+
+		CORS: CORS{
+			AllowedOrigins:   getList("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods:   getList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PATCH", "DELETE"}),
+			AllowedHeaders:   getList("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "Idempotency-Key"}),
+			ExposedHeaders:   getList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Idempotent-Replayed", "Deprecation", "Sunset", "Link"}),
+			AllowCredentials: getBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+
+		Addr:              getString("ADDR", ":8080"),
+		ReadTimeout:       getDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout: getDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      getDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		MaxHeaderBytes:    getInt("HTTP_MAX_HEADER_BYTES", 1<<20),
+		ShutdownDelay:     getDuration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout:   getDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+
+		GRPCAddr: getString("GRPC_ADDR", ":9090"),
+
+		EventsRetention: getDuration("EVENTS_RETENTION", 7*24*time.Hour),
+		Webhooks: Webhooks{
+			Interval:    getDuration("WEBHOOK_INTERVAL", 2*time.Second),
+			Timeout:     getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts: getInt("WEBHOOK_MAX_ATTEMPTS", 10),
+			Backoff:     getDuration("WEBHOOK_BACKOFF", 5*time.Second),
+			MaxBackoff:  getDuration("WEBHOOK_MAX_BACKOFF", time.Hour),
+
+			AllowPrivate: getBool("WEBHOOK_ALLOW_PRIVATE", false),
+		},
+	}
+}
+
+func getString(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getList splits a comma-separated value, dropping empty items
+func getList(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+func getInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// getDate parses a date such as 2027-04-30, as midnight UTC
+func getDate(key string, fallback time.Time) time.Time {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return fallback
+	}
+	return t
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
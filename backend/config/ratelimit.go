@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRateLimits throttles writes harder than reads and leaves the
+// probes and metrics alone. The deprecated unversioned aliases are routes
+// of their own and need their own entries.
+const DefaultRateLimits = "default=50/s:100," +
+	"POST /v1/books/add=5/s:20,POST /books/add=5/s:20," +
+	"POST /v1/books/batch=1/s:5," +
+	"POST /v1/books/labels=1/s:5," +
+	"POST /graphql=10/s:20," +
+	"GET /healthz=off,GET /readyz=off,GET /metrics=off"
+
+// RateLimit is a token bucket refilled at PerSecond tokens a second holding
+// at most Burst tokens. A zero RateLimit means unlimited.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Unlimited reports whether the limit is switched off
+func (r RateLimit) Unlimited() bool {
+	return r.PerSecond <= 0
+}
+
+// ParseRateLimits parses entries such as
+//
+//	default=50/s:100,POST /v1/books/add=30/m:10,GET /metrics=off
+//
+// keyed by "default" or the method and gin route. The burst defaults to
+// the number of requests per unit.
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: expected route=rate", entry)
+		}
+		route = strings.Join(strings.Fields(route), " ")
+		spec = strings.TrimSpace(spec)
+		if spec == "off" {
+			limits[route] = RateLimit{}
+			continue
+		}
+
+		spec, burstText, hasBurst := strings.Cut(spec, ":")
+		countText, unit, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: expected count/unit", entry)
+		}
+		count, err := strconv.ParseFloat(countText, 64)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("rate limit %q: invalid count", entry)
+		}
+		var per time.Duration
+		switch unit {
+		case "s":
+			per = time.Second
+		case "m":
+			per = time.Minute
+		case "h":
+			per = time.Hour
+		default:
+			return nil, fmt.Errorf("rate limit %q: unit must be s, m or h", entry)
+		}
+
+		burst := max(int(count), 1)
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstText); err != nil || burst < 1 {
+				return nil, fmt.Errorf("rate limit %q: invalid burst", entry)
+			}
+		}
+		limits[route] = RateLimit{PerSecond: count / per.Seconds(), Burst: burst}
+	}
+	return limits, nil
+}
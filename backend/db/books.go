@@ -0,0 +1,408 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/kushalpraja/library-api/models"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no book matches
+var ErrNotFound = errors.New("book not found")
+
+// QueryTimeout bounds every call into the data layer, on top of any
+// deadline the caller's context already has
+var QueryTimeout = 5 * time.Second
+
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, QueryTimeout)
+}
+
+const bookColumns = "id, Book_name, Author, ISBN, Year, created_at, updated_at"
+
+// scanBook reads a row selected with bookColumns
+func scanBook(row interface{ Scan(...any) error }) (Book, error) {
+	var book Book
+	var created, updated int64
+	err := row.Scan(&book.ID, &book.BookName, &book.Author, &book.ISBN, &book.Year, &created, &updated)
+	book.CreatedAt, book.UpdatedAt = unixTime(created), unixTime(updated)
+	return book, err
+}
+
+func scanBooks(rows *sql.Rows) ([]Book, error) {
+	defer rows.Close()
+	books := []Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+// ListBooks returns every book in the catalogue
+func ListBooks(ctx context.Context) ([]Book, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := Query(ctx, "books.list", "SELECT "+bookColumns+" FROM library")
+	if err != nil {
+		return nil, err
+	}
+	return scanBooks(rows)
+}
+
+// SearchBooks matches titles and authors containing q, or the exact ISBN or
+// year
+func SearchBooks(ctx context.Context, q string) ([]Book, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	pattern := containing(q)
+	rows, err := Query(ctx, "books.search", "SELECT "+bookColumns+` FROM library
+		WHERE Book_name LIKE ? ESCAPE '\' OR Author LIKE ? ESCAPE '\' OR CAST(ISBN AS TEXT) = ?
+			OR (Year > 0 AND CAST(Year AS TEXT) = ?)`, pattern, pattern, q, q)
+	if err != nil {
+		return nil, err
+	}
+	return scanBooks(rows)
+}
+
+// likeEscaper escapes the LIKE wildcards, for queries with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containing is the LIKE pattern matching values that contain s literally
+func containing(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// GetBook returns the book with the given id
+func GetBook(ctx context.Context, id int64) (Book, error) {
+	return getBook(ctx, DB, id)
+}
+
+func getBook(ctx context.Context, q Querier, id int64) (Book, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	book, err := scanBook(queryRowOn(ctx, q, "books.get", "SELECT "+bookColumns+" FROM library WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, ErrNotFound
+	}
+	return book, err
+}
+
+// BookFilter narrows FilterBooks. Zero fields don't filter.
+type BookFilter struct {
+	// Search matches titles and authors containing it
+	Search   string
+	Author   string
+	ISBN     int
+	YearFrom int
+	YearTo   int
+}
+
+func (f BookFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.Search != "" {
+		pattern := containing(f.Search)
+		conds = append(conds, `(Book_name LIKE ? ESCAPE '\' OR Author LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if f.Author != "" {
+		conds = append(conds, "Author = ?")
+		args = append(args, f.Author)
+	}
+	if f.ISBN != 0 {
+		conds = append(conds, "ISBN = ?")
+		args = append(args, f.ISBN)
+	}
+	if f.YearFrom != 0 {
+		conds = append(conds, "Year >= ?")
+		args = append(args, f.YearFrom)
+	}
+	if f.YearTo != 0 {
+		conds = append(conds, "Year <= ?")
+		args = append(args, f.YearTo)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// FilterBooks returns one page of the books matching f in id order, and
+// how many match in total
+func FilterBooks(ctx context.Context, f BookFilter, limit, offset int) ([]Book, int, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	where, args := f.where()
+	var total int
+	if err := QueryRow(ctx, "books.filter_count", "SELECT COUNT(*) FROM library"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := Query(ctx, "books.filter", "SELECT "+bookColumns+" FROM library"+where+" ORDER BY id LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	books, err := scanBooks(rows)
+	return books, total, err
+}
+
+// BooksByAuthors returns the books of all the given authors in one query,
+// for resolvers that would otherwise query once per author
+func BooksByAuthors(ctx context.Context, authors []string) ([]Book, error) {
+	if len(authors) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	args := make([]any, len(authors))
+	for i, a := range authors {
+		args[i] = a
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(authors)), ", ")
+	rows, err := Query(ctx, "books.by_authors", "SELECT "+bookColumns+" FROM library WHERE Author IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanBooks(rows)
+}
+
+// ListAuthors returns one page of the distinct author names in name order
+func ListAuthors(ctx context.Context, limit, offset int) ([]string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := Query(ctx, "authors.list", "SELECT DISTINCT Author FROM library ORDER BY Author LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	authors := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		authors = append(authors, name)
+	}
+	return authors, rows.Err()
+}
+
+// CountBooks returns the number of books in the catalogue
+func CountBooks(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var n int64
+	err := QueryRow(ctx, "books.count", "SELECT COUNT(*) FROM library").Scan(&n)
+	return n, err
+}
+
+// AddBook inserts book, stamping its creation time, and returns its id
+func AddBook(ctx context.Context, book Book) (int64, error) {
+	var id int64
+	err := WithTx(ctx, func(tx *Tx) error {
+		var err error
+		id, err = tx.AddBook(ctx, book)
+		return err
+	})
+	return id, err
+}
+
+func addBook(ctx context.Context, q Querier, book Book) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().Unix()
+	result, err := execOn(ctx, q, "books.add", `INSERT INTO library (Book_name, Author, ISBN, Year, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		book.BookName, book.Author, book.ISBN, book.Year, now, now)
+	if err != nil {
+		return 0, err
+	}
+	if book.ID, err = result.LastInsertId(); err != nil {
+		return 0, err
+	}
+	book.CreatedAt, book.UpdatedAt = unixTime(now), unixTime(now)
+	return book.ID, recordEvent(ctx, q, models.BookCreated, book)
+}
+
+// CreateBook inserts book and returns it as stored, with its id and times
+func CreateBook(ctx context.Context, book Book) (Book, error) {
+	err := WithTx(ctx, func(tx *Tx) error {
+		id, err := tx.AddBook(ctx, book)
+		if err != nil {
+			return err
+		}
+		book, err = tx.GetBook(ctx, id)
+		return err
+	})
+	return book, err
+}
+
+// booksTitled returns the books titled title
+func booksTitled(ctx context.Context, q Querier, title string) ([]Book, error) {
+	rows, err := queryOn(ctx, q, "books.by_title", "SELECT "+bookColumns+" FROM library WHERE Book_name = ?", title)
+	if err != nil {
+		return nil, err
+	}
+	return scanBooks(rows)
+}
+
+// UpdateField sets column to value on every book titled title. column must
+// be one of Book_name, Author, ISBN or Year.
+func UpdateField(ctx context.Context, title, column string, value any) error {
+	switch column {
+	case "Book_name", "Author", "ISBN", "Year":
+	default:
+		return errors.New("invalid column " + column)
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, func(tx *Tx) error {
+		books, err := booksTitled(ctx, tx.tx, title)
+		if err != nil {
+			return err
+		}
+		if len(books) == 0 {
+			return ErrNotFound
+		}
+		if _, err := execOn(ctx, tx.tx, "books.edit", "UPDATE library SET "+column+" = ?, updated_at = ? WHERE Book_name = ?",
+			value, time.Now().Unix(), title); err != nil {
+			return err
+		}
+		tx.changed = true
+		for _, book := range books {
+			// read back by id, the title itself may have changed
+			if book, err = getBook(ctx, tx.tx, book.ID); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx.tx, models.BookUpdated, book); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByTitle removes every book titled title
+func DeleteByTitle(ctx context.Context, title string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, func(tx *Tx) error {
+		books, err := booksTitled(ctx, tx.tx, title)
+		if err != nil {
+			return err
+		}
+		if len(books) == 0 {
+			return ErrNotFound
+		}
+		if _, err := execOn(ctx, tx.tx, "books.delete", "DELETE FROM library WHERE Book_name = ?", title); err != nil {
+			return err
+		}
+		tx.changed = true
+		for _, book := range books {
+			if err := recordEvent(ctx, tx.tx, models.BookDeleted, book); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteBook removes the book with the given id
+func DeleteBook(ctx context.Context, id int64) error {
+	return WithTx(ctx, func(tx *Tx) error {
+		return tx.DeleteBook(ctx, id)
+	})
+}
+
+func deleteBook(ctx context.Context, q Querier, id int64) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	// the deleted event carries the book as it was
+	book, err := getBook(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	result, err := execOn(ctx, q, "books.delete_id", "DELETE FROM library WHERE id = ?", id)
+	if err := affectedOrNotFound(result, err); err != nil {
+		return err
+	}
+	return recordEvent(ctx, q, models.BookDeleted, book)
+}
+
+// UpdateBook overwrites the stored fields of the book with book.ID and
+// stamps its update time
+func UpdateBook(ctx context.Context, book Book) error {
+	return WithTx(ctx, func(tx *Tx) error {
+		return tx.UpdateBook(ctx, book)
+	})
+}
+
+func updateBook(ctx context.Context, q Querier, book Book) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := execOn(ctx, q, "books.update", `UPDATE library SET Book_name = ?, Author = ?, ISBN = ?, Year = ?, updated_at = ?
+		WHERE id = ?`,
+		book.BookName, book.Author, book.ISBN, book.Year, time.Now().Unix(), book.ID)
+	if err := affectedOrNotFound(result, err); err != nil {
+		return err
+	}
+	stored, err := getBook(ctx, q, book.ID)
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, q, models.BookUpdated, stored)
+}
+
+// PatchBook copies the fields set in req onto the book with id and returns
+// it as stored, update time included
+func PatchBook(ctx context.Context, id int64, req models.UpdateBookRequest) (Book, error) {
+	var book Book
+	err := WithTx(ctx, func(tx *Tx) error {
+		var err error
+		if book, err = tx.GetBook(ctx, id); err != nil {
+			return err
+		}
+		book.Apply(req)
+		if err := tx.UpdateBook(ctx, book); err != nil {
+			return err
+		}
+		book, err = tx.GetBook(ctx, id)
+		return err
+	})
+	return book, err
+}
+
+func affectedOrNotFound(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1,33 +1,42 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
-	"log"
+	"time"
 )
 
 var DB *sql.DB
 
-func Connect() {
+// busyTimeout is how long a statement waits for another connection's lock
+const busyTimeout = 5 * time.Second
+
+func Connect() error {
+	return Open("./../example.db")
+}
+
+// Open connects to the database file at path and migrates it, tests use
+// it with a file in a temporary directory.
+//
+// Transactions take the write lock when they begin rather than on their
+// first write, so two that read before writing can't deadlock on the
+// upgrade, and a writer waits up to busyTimeout for the lock instead of
+// failing with "database is locked".
+func Open(path string) error {
 	var err error
-	DB, err = sql.Open("sqlite3", "./../example.db")
+	DB, err = sql.Open("sqlite3", fmt.Sprintf("%s?_txlock=immediate&_busy_timeout=%d", path, busyTimeout.Milliseconds()))
 	if err != nil {
-		log.Fatal("Failed to open database", err)
+		return fmt.Errorf("open database: %w", err)
 	}
 
 	if err := DB.Ping(); err != nil {
-		log.Fatal("Failed to ping database", err)
+		return fmt.Errorf("ping database: %w", err)
 	}
 
-	createTable := `
-	CREATE TABLE IF NOT EXISTS library (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		Book_name TEXT NOT NULL,
-		Author TEXT NOT NULL,
-		ISBN INTEGER NOT NULL
-	);`
-
-	if _, err := DB.Exec(createTable); err != nil {
-		log.Fatal("Failed to create table", err)
+	if err := Migrate(context.Background()); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
 	}
+	return nil
 }
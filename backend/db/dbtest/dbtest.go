@@ -0,0 +1,55 @@
+// Package dbtest sets up what the tests of packages built on db share: a
+// fresh database for each test, quiet logging and a few fixtures.
+package dbtest
+
+import (
+	"context"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/validation"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Main runs the tests of a package with logging discarded and the custom
+// binding rules registered, call it from TestMain
+func Main(m *testing.M) {
+	if err := validation.Register(); err != nil {
+		panic(err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// Open points db.DB at a new, migrated database that is closed and removed
+// when t ends
+func Open(t testing.TB) {
+	t.Helper()
+	if err := db.Open(filepath.Join(t.TempDir(), "library.db")); err != nil {
+		t.Fatal(err)
+	}
+	conn := db.DB
+	t.Cleanup(func() { conn.Close() })
+}
+
+// AddBooks stores a book with each of the given titles and returns them as
+// stored
+func AddBooks(t testing.TB, titles ...string) []db.Book {
+	t.Helper()
+	books := make([]db.Book, 0, len(titles))
+	for _, title := range titles {
+		book, err := db.CreateBook(context.Background(), db.NewBook(models.CreateBookRequest{
+			BookName: title,
+			Author:   "Someone",
+			ISBN:     9780441172719,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		books = append(books, book)
+	}
+	return books
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/kushalpraja/library-api/models"
+	"sync"
+	"time"
+)
+
+// recordEvent adds a change of book to the events outbox. It runs on the
+// transaction making the change, so the event exists if and only if the
+// change was committed.
+func recordEvent(ctx context.Context, q Querier, typ string, book Book) error {
+	data, err := json.Marshal(book.Model())
+	if err != nil {
+		return err
+	}
+	_, err = execOn(ctx, q, "events.record", "INSERT INTO events (type, book_id, book, created_at) VALUES (?, ?, ?, ?)",
+		typ, book.ID, data, time.Now().Unix())
+	return err
+}
+
+var (
+	eventsMu       sync.Mutex
+	eventsRecorded = make(chan struct{})
+)
+
+// EventsRecorded returns a channel closed the next time a transaction that
+// recorded events commits
+func EventsRecorded() <-chan struct{} {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	return eventsRecorded
+}
+
+func notifyEvents() {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	close(eventsRecorded)
+	eventsRecorded = make(chan struct{})
+}
+
+// EventsAfter returns up to limit events with an id above after, oldest first
+func EventsAfter(ctx context.Context, after int64, limit int) ([]models.Event, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := Query(ctx, "events.after", "SELECT id, type, book_id, book, created_at FROM events WHERE id > ? ORDER BY id LIMIT ?", after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []models.Event{}
+	for rows.Next() {
+		var e models.Event
+		var data []byte
+		var created int64
+		if err := rows.Scan(&e.ID, &e.Type, &e.BookID, &data, &created); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &e.Book); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = unixTime(created)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// LatestEventID returns the id of the newest event, 0 if there is none
+func LatestEventID(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := QueryRow(ctx, "events.latest", "SELECT COALESCE(MAX(id), 0) FROM events").Scan(&id)
+	return id, err
+}
+
+// FirstEventID returns the id of the oldest event still kept, or the id
+// the next event will get when there are none. A client that saw events
+// up to an id below FirstEventID-1 missed some that were purged.
+func FirstEventID(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := QueryRow(ctx, "events.first", `SELECT COALESCE(
+		(SELECT MIN(id) FROM events),
+		(SELECT seq + 1 FROM sqlite_sequence WHERE name = 'events'),
+		1)`).Scan(&id)
+	return id, err
+}
+
+// PurgeEvents deletes events recorded before cutoff, except those a
+// webhook hasn't been sent yet
+func PurgeEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := Exec(ctx, "events.purge", `DELETE FROM events
+		WHERE created_at < ? AND id <= COALESCE((SELECT MIN(last_event_id) FROM webhooks), id)`, cutoff.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord is what is stored for an Idempotency-Key
+type IdempotencyRecord struct {
+	RequestHash string
+	// Status is 0 while the first request with the key is still running
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// ClaimIdempotencyKey reserves key within scope for a request with the given
+// hash. If the key is new, its record is older than ttl, or it was claimed
+// more than lease ago by a request that never stored a response, it is
+// claimed and nil is returned; otherwise the existing record is returned.
+func ClaimIdempotencyKey(ctx context.Context, scope, key, hash string, ttl, lease time.Duration) (*IdempotencyRecord, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	var existing *IdempotencyRecord
+	err := WithTx(ctx, func(tx *Tx) error {
+		if _, err := execOn(ctx, tx.tx, "idempotency.expire",
+			"DELETE FROM idempotency_keys WHERE scope = ? AND key = ? AND (created_at < ? OR (status = 0 AND created_at < ?))",
+			scope, key, now.Add(-ttl).Unix(), now.Add(-lease).Unix()); err != nil {
+			return err
+		}
+		result, err := execOn(ctx, tx.tx, "idempotency.claim",
+			"INSERT OR IGNORE INTO idempotency_keys (scope, key, request_hash, created_at) VALUES (?, ?, ?, ?)",
+			scope, key, hash, now.Unix())
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 1 {
+			return err
+		}
+
+		var rec IdempotencyRecord
+		var created int64
+		err = queryRowOn(ctx, tx.tx, "idempotency.get",
+			"SELECT request_hash, status, content_type, body, created_at FROM idempotency_keys WHERE scope = ? AND key = ?",
+			scope, key).Scan(&rec.RequestHash, &rec.Status, &rec.ContentType, &rec.Body, &created)
+		if err != nil {
+			return err
+		}
+		rec.CreatedAt = time.Unix(created, 0)
+		existing = &rec
+		return nil
+	})
+	return existing, err
+}
+
+// SaveIdempotentResponse stores the response of a claimed key for replay
+func SaveIdempotentResponse(ctx context.Context, scope, key string, status int, contentType string, body []byte) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := Exec(ctx, "idempotency.save",
+		"UPDATE idempotency_keys SET status = ?, content_type = ?, body = ? WHERE scope = ? AND key = ?",
+		status, contentType, body, scope, key)
+	return err
+}
+
+// ReleaseIdempotencyKey forgets a claimed key so the request can be retried
+func ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := Exec(ctx, "idempotency.release", "DELETE FROM idempotency_keys WHERE scope = ? AND key = ?", scope, key)
+	return err
+}
+
+// PurgeIdempotencyKeys deletes records created before cutoff
+func PurgeIdempotencyKeys(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := Exec(ctx, "idempotency.purge", "DELETE FROM idempotency_keys WHERE created_at < ?", cutoff.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
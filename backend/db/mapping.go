@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/kushalpraja/library-api/models"
+	"strings"
+	"time"
+)
+
+// Book is a row of the library table. Handlers map it to and from the API
+// models, so a column added here stays internal until a model exposes it.
+type Book struct {
+	ID        int64
+	BookName  string
+	Author    string
+	ISBN      int
+	Year      int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NewBook builds the row stored for a create request. The timestamps are
+// set when it is inserted.
+func NewBook(req models.CreateBookRequest) Book {
+	return Book{
+		BookName: strings.TrimSpace(req.BookName),
+		Author:   strings.TrimSpace(req.Author),
+		ISBN:     req.ISBN,
+		Year:     req.Year,
+	}
+}
+
+// Apply copies the fields set in req onto the row
+func (b *Book) Apply(req models.UpdateBookRequest) {
+	if req.BookName != nil {
+		b.BookName = strings.TrimSpace(*req.BookName)
+	}
+	if req.Author != nil {
+		b.Author = strings.TrimSpace(*req.Author)
+	}
+	if req.ISBN != nil {
+		b.ISBN = *req.ISBN
+	}
+	if req.Year != nil {
+		b.Year = *req.Year
+	}
+}
+
+// Model returns the row as the API represents it
+func (b Book) Model() models.Book {
+	return models.Book{
+		ID:        b.ID,
+		BookName:  b.BookName,
+		Author:    b.Author,
+		ISBN:      b.ISBN,
+		Year:      b.Year,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+// Models maps a list of rows with Model
+func Models(books []Book) []models.Book {
+	out := make([]models.Book, len(books))
+	for i, b := range books {
+		out[i] = b.Model()
+	}
+	return out
+}
+
+// unixTime reads a timestamp column, stored as Unix seconds
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/kushalpraja/library-api/metrics"
+)
+
+var (
+	queryDuration = metrics.NewHistogram("library_db_query_duration_seconds",
+		"Time taken by database statements, by operation.",
+		metrics.DefBuckets, "op")
+	queryErrors = metrics.NewCounter("library_db_query_errors_total",
+		"Database statements that returned an error, by operation.",
+		"op")
+)
+
+// stats reads DB's pool statistics, reporting false before Connect
+func stats() (sql.DBStats, bool) {
+	if DB == nil {
+		return sql.DBStats{}, false
+	}
+	return DB.Stats(), true
+}
+
+var (
+	_ = metrics.NewGaugeFunc("library_db_open_connections", "Established connections, both in use and idle.", func() (float64, bool) {
+		s, ok := stats()
+		return float64(s.OpenConnections), ok
+	})
+	_ = metrics.NewGaugeFunc("library_db_in_use_connections", "Connections currently in use.", func() (float64, bool) {
+		s, ok := stats()
+		return float64(s.InUse), ok
+	})
+	_ = metrics.NewGaugeFunc("library_db_idle_connections", "Idle connections in the pool.", func() (float64, bool) {
+		s, ok := stats()
+		return float64(s.Idle), ok
+	})
+	_ = metrics.NewCounterFunc("library_db_wait_count_total", "Times a caller waited for a free connection.", func() (float64, bool) {
+		s, ok := stats()
+		return float64(s.WaitCount), ok
+	})
+	_ = metrics.NewCounterFunc("library_db_wait_duration_seconds_total", "Time spent waiting for a free connection.", func() (float64, bool) {
+		s, ok := stats()
+		return s.WaitDuration.Seconds(), ok
+	})
+
+	// counted on every scrape, which is cheap for a table this size
+	_ = metrics.NewGaugeFunc("library_books", "Books in the catalogue.", func() (float64, bool) {
+		if DB == nil {
+			return 0, false
+		}
+		n, err := CountBooks(context.Background())
+		return float64(n), err == nil
+	})
+)
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrations are applied in order; the schema version stored in SQLite's
+// user_version pragma is the number of migrations applied. Only ever
+// append to this list.
+var migrations = []string{
+	// 1: the original library table
+	`CREATE TABLE IF NOT EXISTS library (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		Book_name TEXT NOT NULL,
+		Author TEXT NOT NULL,
+		ISBN INTEGER NOT NULL
+	)`,
+	// 2: responses stored for Idempotency-Key replays
+	`CREATE TABLE idempotency_keys (
+		scope TEXT NOT NULL,
+		key TEXT NOT NULL,
+		request_hash TEXT NOT NULL,
+		status INTEGER NOT NULL DEFAULT 0,
+		content_type TEXT NOT NULL DEFAULT '',
+		body BLOB,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (scope, key)
+	);
+	CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at)`,
+	// 3: publication year, 0 when unknown
+	`ALTER TABLE library ADD COLUMN Year INTEGER NOT NULL DEFAULT 0`,
+	// 4: creation and update times in Unix seconds, existing books get the
+	// time of the migration
+	`ALTER TABLE library ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE library ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+	UPDATE library SET created_at = unixepoch(), updated_at = unixepoch()`,
+	// 5: outbox of book changes, written in the same transaction as the
+	// change, and the webhooks it is delivered to. AUTOINCREMENT keeps
+	// event ids from being reused after old events are purged, so clients
+	// can resume from the last id they saw.
+	`CREATE TABLE events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		book_id INTEGER NOT NULL,
+		book BLOB NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX events_created_at ON events (created_at);
+	CREATE TABLE webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		last_event_id INTEGER NOT NULL DEFAULT 0,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	)`,
+}
+
+// SchemaVersion is the schema version this build expects
+var SchemaVersion = len(migrations)
+
+// CurrentVersion reads the schema version of the connected database
+func CurrentVersion(ctx context.Context) (int, error) {
+	var version int
+	err := DB.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// Migrate applies the migrations the database hasn't seen yet, each in its
+// own transaction together with the version bump
+func Migrate(ctx context.Context) error {
+	current, err := CurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if current > SchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build (%d)", current, SchemaVersion)
+	}
+
+	for version := current + 1; version <= SchemaVersion; version++ {
+		tx, err := DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		// pragmas can't take parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Querier is what statements run on: DB itself or a transaction
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Query, QueryRow and Exec run a statement against DB with the caller's
+// context and log it under op, a short name for the operation such as
+// "books.list". Queries are logged at debug level, failures at error, and
+// their latency is recorded in the query metrics.
+
+func Query(ctx context.Context, op, query string, args ...any) (*sql.Rows, error) {
+	return queryOn(ctx, DB, op, query, args...)
+}
+
+// QueryRow can only time the query itself; errors surface on Scan
+func QueryRow(ctx context.Context, op, query string, args ...any) *sql.Row {
+	return queryRowOn(ctx, DB, op, query, args...)
+}
+
+func Exec(ctx context.Context, op, query string, args ...any) (sql.Result, error) {
+	return execOn(ctx, DB, op, query, args...)
+}
+
+func queryOn(ctx context.Context, q Querier, op, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args...)
+	logQuery(ctx, op, start, err)
+	return rows, err
+}
+
+func queryRowOn(ctx context.Context, q Querier, op, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := q.QueryRowContext(ctx, query, args...)
+	logQuery(ctx, op, start, row.Err())
+	return row
+}
+
+func execOn(ctx context.Context, q Querier, op, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := q.ExecContext(ctx, query, args...)
+	logQuery(ctx, op, start, err)
+	return result, err
+}
+
+func logQuery(ctx context.Context, op string, start time.Time, err error) {
+	duration := time.Since(start)
+	queryDuration.Observe(duration.Seconds(), op)
+	elapsed := float64(duration.Microseconds()) / 1000
+	if errors.Is(err, context.Canceled) {
+		slog.InfoContext(ctx, "query canceled", "op", op, "duration_ms", elapsed)
+		return
+	}
+	if err != nil {
+		queryErrors.Inc(op)
+		slog.ErrorContext(ctx, "query failed", "op", op, "duration_ms", elapsed, "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "query", "op", op, "duration_ms", elapsed)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Tx runs data layer operations inside one transaction
+type Tx struct {
+	tx         *sql.Tx
+	savepoints int
+	// changed is set once a change, and so an event, has been written
+	changed bool
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and
+// rolling back otherwise. The transaction is also rolled back if ctx is
+// canceled before the commit.
+func WithTx(ctx context.Context, fn func(*Tx) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	t := &Tx{tx: tx}
+	if err := fn(t); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if t.changed {
+		notifyEvents()
+	}
+	return nil
+}
+
+func (t *Tx) GetBook(ctx context.Context, id int64) (Book, error) {
+	return getBook(ctx, t.tx, id)
+}
+
+func (t *Tx) AddBook(ctx context.Context, book Book) (int64, error) {
+	t.changed = true
+	return addBook(ctx, t.tx, book)
+}
+
+func (t *Tx) UpdateBook(ctx context.Context, book Book) error {
+	t.changed = true
+	return updateBook(ctx, t.tx, book)
+}
+
+func (t *Tx) DeleteBook(ctx context.Context, id int64) error {
+	t.changed = true
+	return deleteBook(ctx, t.tx, id)
+}
+
+// Savepoint runs fn so that if it fails only its own changes are undone
+// and the transaction stays usable
+func (t *Tx) Savepoint(ctx context.Context, fn func() error) error {
+	t.savepoints++
+	name := fmt.Sprintf("sp%d", t.savepoints)
+	if _, err := execOn(ctx, t.tx, "tx.savepoint", "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		// ROLLBACK TO leaves the savepoint open, RELEASE closes it
+		if _, rbErr := execOn(ctx, t.tx, "tx.rollback_to", "ROLLBACK TO "+name); rbErr != nil {
+			return rbErr
+		}
+		if _, relErr := execOn(ctx, t.tx, "tx.release", "RELEASE "+name); relErr != nil {
+			return relErr
+		}
+		return err
+	}
+	_, err := execOn(ctx, t.tx, "tx.release", "RELEASE "+name)
+	return err
+}
@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"context"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	"github.com/kushalpraja/library-api/models"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dbtest.Main(m)
+}
+
+// transactions that read before they write used to fail with "database is
+// locked" when two of them tried to upgrade their read locks at once
+func TestConcurrentWriters(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	book := dbtest.AddBooks(t, "Dune")[0]
+
+	const writers, writes = 8, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				year := 1965 + i
+				if _, err := db.PatchBook(ctx, book.ID, models.UpdateBookRequest{Year: &year}); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent patch: %v", err)
+	}
+
+	events, err := db.EventsAfter(ctx, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + writers*writes; len(events) != want {
+		t.Errorf("recorded %d events, want %d", len(events), want)
+	}
+}
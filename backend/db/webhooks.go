@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"github.com/kushalpraja/library-api/models"
+	"time"
+)
+
+const webhookColumns = "id, url, secret, last_event_id, attempts, next_attempt_at, last_error, created_at"
+
+func scanWebhooks(ctx context.Context, op, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := Query(ctx, op, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var h models.Webhook
+		var next, created int64
+		if err := rows.Scan(&h.ID, &h.URL, &h.Secret, &h.LastEventID, &h.Attempts, &next, &h.LastError, &created); err != nil {
+			return nil, err
+		}
+		h.NextAttemptAt, h.CreatedAt = unixTime(next), unixTime(created)
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+// AddWebhook registers a webhook. It is sent the events recorded from now
+// on, not the ones before.
+func AddWebhook(ctx context.Context, url, secret string) (models.Webhook, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().Unix()
+	result, err := Exec(ctx, "webhooks.add", `INSERT INTO webhooks (url, secret, last_event_id, created_at)
+		VALUES (?, ?, (SELECT COALESCE(MAX(id), 0) FROM events), ?)`,
+		url, secret, now)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	hooks, err := scanWebhooks(ctx, "webhooks.get", "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if len(hooks) == 0 {
+		return models.Webhook{}, ErrNotFound
+	}
+	return hooks[0], nil
+}
+
+// ListWebhooks returns every registered webhook, secrets included
+func ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return scanWebhooks(ctx, "webhooks.list", "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+}
+
+// DueWebhooks returns the webhooks that have events after their last one
+// and aren't waiting to retry
+func DueWebhooks(ctx context.Context, now time.Time) ([]models.Webhook, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return scanWebhooks(ctx, "webhooks.due", "SELECT "+webhookColumns+` FROM webhooks
+		WHERE next_attempt_at <= ? AND last_event_id < (SELECT COALESCE(MAX(id), 0) FROM events)
+		ORDER BY id`, now.Unix())
+}
+
+// DeleteWebhook unregisters the webhook with the given id
+func DeleteWebhook(ctx context.Context, id int64) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := Exec(ctx, "webhooks.delete", "DELETE FROM webhooks WHERE id = ?", id)
+	return affectedOrNotFound(result, err)
+}
+
+// AdvanceWebhook moves the webhook past eventID, either delivered or given
+// up on with lastError, and clears its retry state
+func AdvanceWebhook(ctx context.Context, id, eventID int64, lastError string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := Exec(ctx, "webhooks.advance",
+		"UPDATE webhooks SET last_event_id = ?, attempts = 0, next_attempt_at = 0, last_error = ? WHERE id = ?",
+		eventID, lastError, id)
+	return err
+}
+
+// RetryWebhook records a failed attempt and when to try again
+func RetryWebhook(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := Exec(ctx, "webhooks.retry",
+		"UPDATE webhooks SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		attempts, next.Unix(), lastError, id)
+	return err
+}
@@ -0,0 +1,54 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"github.com/kushalpraja/library-api/validation"
+	"log/slog"
+)
+
+// codedError is a resolver error carrying the same code, and for
+// validation failures the same fields, as the REST error bodies. They are
+// reported under the error's extensions.
+type codedError struct {
+	message string
+	code    string
+	fields  map[string]string
+}
+
+func (e *codedError) Error() string { return e.message }
+
+func (e *codedError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.code}
+	if e.fields != nil {
+		ext["fields"] = e.fields
+	}
+	return ext
+}
+
+func invalid(field, message string) error {
+	return invalidFields(map[string]string{field: message})
+}
+
+func invalidFields(fields map[string]string) error {
+	return &codedError{
+		message: "Invalid request: " + validation.Summary(fields),
+		code:    "validation_failed",
+		fields:  fields,
+	}
+}
+
+// serverError reports a failure the client can't fix, mapping the context
+// errors to the codes the REST handlers use
+func serverError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &codedError{message: "Request canceled", code: "canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.WarnContext(ctx, "graphql query timed out", "error", err)
+		return &codedError{message: "Database query timed out", code: "timeout"}
+	default:
+		slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+		return err
+	}
+}
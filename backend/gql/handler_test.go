@@ -0,0 +1,217 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	"github.com/kushalpraja/library-api/models"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Main(m)
+}
+
+type result struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// post runs query through Handler against a fresh database
+func post(t *testing.T, query string, variables map[string]any) (int, result) {
+	t.Helper()
+	body, err := json.Marshal(request{Query: query, Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.POST("/graphql", Handler)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var res result
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	return w.Code, res
+}
+
+// nine levels, one more than MaxDepth allows
+const deepFields = `author { books { author { books { author { books { bookName } } } } } }`
+
+func TestLimits(t *testing.T) {
+	dbtest.Open(t)
+
+	for _, tc := range []struct {
+		name      string
+		query     string
+		variables map[string]any
+		// the start of the error, empty when the query is allowed
+		err string
+	}{
+		{
+			name:  "at the depth limit",
+			query: `{ books(first: 1) { items { author { books { author { books { bookName } } } } } } }`,
+		},
+		{
+			name:  "too deep",
+			query: `{ books { items { ` + deepFields + ` } } }`,
+			err:   "query is nested 9 levels deep",
+		},
+		{
+			name:  "too deep through a fragment",
+			query: `{ books { items { ...deep } } } fragment deep on Book { ` + deepFields + ` }`,
+			err:   "query is nested 9 levels deep",
+		},
+		{
+			name:  "too deep through an inline fragment",
+			query: `{ books { items { ... on Book { ` + deepFields + ` } } } }`,
+			err:   "query is nested 9 levels deep",
+		},
+		{
+			name:  "within complexity",
+			query: `{ authors(first: 10) { books { bookName } } }`,
+		},
+		{
+			// 1 + 100 authors * (1 + 10 books * 1)
+			name:  "too complex",
+			query: `{ authors(first: 100) { books { bookName } } }`,
+			err:   "query complexity is 1101",
+		},
+		{
+			name:  "too complex through a fragment",
+			query: `{ authors(first: 100) { ...titles } } fragment titles on Author { books { bookName } }`,
+			err:   "query complexity is 1101",
+		},
+		{
+			name:      "page size from a variable within complexity",
+			query:     `query($n: Int) { authors(first: $n) { books { bookName } } }`,
+			variables: map[string]any{"n": 10},
+		},
+		{
+			name:      "too complex through a variable",
+			query:     `query($n: Int) { authors(first: $n) { books { bookName } } }`,
+			variables: map[string]any{"n": 100},
+			err:       "query complexity is 1101",
+		},
+		{
+			name:  "fragment cycle",
+			query: `{ books { items { ...a } } } fragment a on Book { bookName ...b } fragment b on Book { isbn ...a }`,
+			err:   "fragment a spreads itself",
+		},
+		{
+			name:  "unused fragment cycle",
+			query: `{ books { totalCount } } fragment a on Book { author { books { ...a } } }`,
+			err:   "fragment a spreads itself",
+		},
+		{
+			name:  "introspection is free",
+			query: `{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name } } } } } } } }`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, res := post(t, tc.query, tc.variables)
+			if tc.err == "" {
+				if status != http.StatusOK {
+					t.Fatalf("status %d: %+v", status, res.Errors)
+				}
+				return
+			}
+			if status != http.StatusBadRequest || len(res.Errors) != 1 || !strings.HasPrefix(res.Errors[0].Message, tc.err) {
+				t.Fatalf("status %d, errors %+v, want 400 with %q", status, res.Errors, tc.err)
+			}
+			if res.Data != nil {
+				t.Errorf("rejected query returned data %v", res.Data)
+			}
+		})
+	}
+}
+
+// queryLog records the op of every query the db package logs
+type queryLog struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (l *queryLog) Enabled(context.Context, slog.Level) bool { return true }
+func (l *queryLog) WithAttrs([]slog.Attr) slog.Handler       { return l }
+func (l *queryLog) WithGroup(string) slog.Handler            { return l }
+
+func (l *queryLog) Handle(_ context.Context, r slog.Record) error {
+	if r.Message != "query" {
+		return nil
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "op" {
+			l.mu.Lock()
+			l.ops = append(l.ops, a.Value.String())
+			l.mu.Unlock()
+		}
+		return true
+	})
+	return nil
+}
+
+func (l *queryLog) count(op string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, o := range l.ops {
+		if o == op {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAuthorBooksBatched(t *testing.T) {
+	dbtest.Open(t)
+	for _, b := range []struct{ title, author string }{
+		{"Dune", "Frank Herbert"},
+		{"Children of Dune", "Frank Herbert"},
+		{"Emma", "Jane Austen"},
+		{"Persuasion", "Jane Austen"},
+		{"Ulysses", "James Joyce"},
+	} {
+		if _, err := db.CreateBook(context.Background(), db.NewBook(models.CreateBookRequest{
+			BookName: b.title,
+			Author:   b.author,
+			ISBN:     9780441172719,
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := &queryLog{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(log))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	status, res := post(t, `{ authors { name books { bookName } } }`, nil)
+	if status != http.StatusOK || len(res.Errors) != 0 {
+		t.Fatalf("status %d, errors %+v", status, res.Errors)
+	}
+	if n := log.count("books.by_authors"); n != 1 {
+		t.Errorf("ran books.by_authors %d times for 3 authors, want once", n)
+	}
+
+	got, _ := json.Marshal(res.Data["authors"])
+	want := `[{"books":[{"bookName":"Dune"},{"bookName":"Children of Dune"}],"name":"Frank Herbert"},` +
+		`{"books":[{"bookName":"Ulysses"}],"name":"James Joyce"},` +
+		`{"books":[{"bookName":"Emma"},{"bookName":"Persuasion"}],"name":"Jane Austen"}]`
+	if string(got) != want {
+		t.Errorf("authors\n%s\nwant\n%s", got, want)
+	}
+}
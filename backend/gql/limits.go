@@ -0,0 +1,204 @@
+package gql
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+	"strings"
+)
+
+// MaxDepth is how deeply fields may be nested in a query
+var MaxDepth = 8
+
+// MaxComplexity bounds the estimated number of fields a query resolves
+var MaxComplexity = 1000
+
+// unpagedListSize is the size assumed for list fields without a page size
+// argument, like Author.books
+const unpagedListSize = 10
+
+// checkLimits rejects the operation if it nests deeper than MaxDepth or
+// its complexity is over MaxComplexity. Each field costs one, times the
+// number of items of the lists it's inside: the page size for paged fields
+// and unpagedListSize for the rest. Introspection fields are free, so
+// tooling can always load the schema. Fragments that spread themselves are
+// rejected too, graphql-go's validation recurses on them until the stack
+// overflows. Operations that don't parse or don't exist are left for
+// graphql.Do to report.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]any) error {
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || def.Name != nil && def.Name.Value == operationName {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if name := fragmentCycle(doc, fragments); name != "" {
+		return fmt.Errorf("fragment %s spreads itself", name)
+	}
+	if op == nil {
+		return nil
+	}
+
+	root := Schema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = Schema.MutationType()
+	}
+	w := walker{fragments: fragments, variables: variables}
+	depth, cost := w.selections(op.SelectionSet, root, false)
+	if depth > MaxDepth {
+		return fmt.Errorf("query is nested %d levels deep, the limit is %d", depth, MaxDepth)
+	}
+	if cost > MaxComplexity {
+		return fmt.Errorf("query complexity is %d, the limit is %d", cost, MaxComplexity)
+	}
+	return nil
+}
+
+// fragmentCycle returns the name of a fragment in doc that spreads itself,
+// directly or through other fragments, or "" if none does
+func fragmentCycle(doc *ast.Document, fragments map[string]*ast.FragmentDefinition) string {
+	// fragments being expanded are false, those known to be acyclic true
+	seen := make(map[string]bool)
+	var spread func(name string) string
+	var selections func(set *ast.SelectionSet) string
+	spread = func(name string) string {
+		frag, ok := fragments[name]
+		if done, visiting := seen[name]; !ok || done {
+			return ""
+		} else if visiting {
+			return name
+		}
+		seen[name] = false
+		if cycle := selections(frag.SelectionSet); cycle != "" {
+			return cycle
+		}
+		seen[name] = true
+		return ""
+	}
+	selections = func(set *ast.SelectionSet) string {
+		if set == nil {
+			return ""
+		}
+		for _, sel := range set.Selections {
+			var cycle string
+			switch sel := sel.(type) {
+			case *ast.Field:
+				cycle = selections(sel.SelectionSet)
+			case *ast.InlineFragment:
+				cycle = selections(sel.SelectionSet)
+			case *ast.FragmentSpread:
+				cycle = spread(sel.Name.Value)
+			}
+			if cycle != "" {
+				return cycle
+			}
+		}
+		return ""
+	}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			if cycle := spread(frag.Name.Value); cycle != "" {
+				return cycle
+			}
+		}
+	}
+	return ""
+}
+
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// selections returns the depth and cost of set, selected on parent. paged
+// is set when parent is a page whose lists were already counted at the
+// paged field.
+func (w walker) selections(set *ast.SelectionSet, parent *graphql.Object, paged bool) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d, c = w.field(sel, parent, paged)
+		case *ast.InlineFragment:
+			d, c = w.selections(sel.SelectionSet, parent, paged)
+		case *ast.FragmentSpread:
+			if frag, ok := w.fragments[sel.Name.Value]; ok {
+				d, c = w.selections(frag.SelectionSet, parent, paged)
+			}
+		}
+		depth = max(depth, d)
+		cost += c
+	}
+	return depth, cost
+}
+
+func (w walker) field(f *ast.Field, parent *graphql.Object, paged bool) (depth, cost int) {
+	if strings.HasPrefix(f.Name.Value, "__") || parent == nil {
+		return 0, 0
+	}
+	def, ok := parent.Fields()[f.Name.Value]
+	if !ok {
+		return 1, 1
+	}
+
+	typ := def.Type
+	if nn, ok := typ.(*graphql.NonNull); ok {
+		typ = nn.OfType
+	}
+	list, isList := typ.(*graphql.List)
+	if isList {
+		typ = list.OfType
+		if nn, ok := typ.(*graphql.NonNull); ok {
+			typ = nn.OfType
+		}
+	}
+	child, _ := typ.(*graphql.Object)
+
+	items := 1
+	childPaged := false
+	if size, ok := w.pageSize(f, def); ok {
+		items = size
+		// a page object holds the list the size applies to
+		childPaged = !isList
+	} else if isList && !paged {
+		items = unpagedListSize
+	}
+	d, c := w.selections(f.SelectionSet, child, childPaged)
+	return d + 1, 1 + items*c
+}
+
+// pageSize returns the first argument of f, or its default, if def takes one
+func (w walker) pageSize(f *ast.Field, def *graphql.FieldDefinition) (int, bool) {
+	for _, arg := range def.Args {
+		if arg.PrivateName != "first" {
+			continue
+		}
+		size, _ := arg.DefaultValue.(int)
+		for _, a := range f.Arguments {
+			if a.Name.Value != "first" {
+				continue
+			}
+			switch v := a.Value.(type) {
+			case *ast.IntValue:
+				size, _ = strconv.Atoi(v.Value)
+			case *ast.Variable:
+				// numbers in the JSON request body decode as float64
+				if n, ok := w.variables[v.Name.Value].(float64); ok {
+					size = int(n)
+				}
+			}
+		}
+		return max(size, 1), true
+	}
+	return 0, false
+}
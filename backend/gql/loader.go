@@ -0,0 +1,67 @@
+package gql
+
+import (
+	"context"
+	"github.com/kushalpraja/library-api/db"
+	"slices"
+	"sync"
+)
+
+type loaderKey struct{}
+
+// loader batches the lookups of one request. Resolvers register the keys
+// they need and return a thunk; the executor resolves every field of a
+// level before running the thunks, so the first thunk to run fetches the
+// keys of all its siblings in one query instead of one query each.
+type loader struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	pending []string
+	books   map[string][]db.Book
+}
+
+func withLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &loader{ctx: ctx, books: make(map[string][]db.Book)})
+}
+
+func loaderFrom(ctx context.Context) *loader {
+	return ctx.Value(loaderKey{}).(*loader)
+}
+
+// booksBy returns a thunk resolving to the books credited to author
+func (l *loader) booksBy(author string) func() (any, error) {
+	l.mu.Lock()
+	if _, done := l.books[author]; !done && !slices.Contains(l.pending, author) {
+		l.pending = append(l.pending, author)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, done := l.books[author]; !done {
+			if err := l.fetchBooks(); err != nil {
+				return nil, serverError(l.ctx, err)
+			}
+		}
+		return l.books[author], nil
+	}
+}
+
+// fetchBooks loads the books of every pending author. Called with l.mu held.
+func (l *loader) fetchBooks() error {
+	authors := l.pending
+	l.pending = nil
+	books, err := db.BooksByAuthors(l.ctx, authors)
+	if err != nil {
+		return err
+	}
+	for _, author := range authors {
+		l.books[author] = []db.Book{}
+	}
+	for _, book := range books {
+		l.books[book.Author] = append(l.books[book.Author], book)
+	}
+	return nil
+}
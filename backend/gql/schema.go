@@ -0,0 +1,354 @@
+// Package gql serves the catalogue over GraphQL. It is a second front end
+// on the data layer: resolvers call the same db functions and validation
+// rules as the REST handlers.
+package gql
+
+import (
+	"context"
+	"github.com/graphql-go/graphql"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/validation"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// author is the source value of the Author type. Authors aren't stored on
+// their own, they are the distinct names on books.
+type author struct {
+	name string
+}
+
+var bookType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Book",
+	Description: "A book in the catalogue",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.ID),
+			Resolve: bookField(func(b db.Book) any { return strconv.FormatInt(b.ID, 10) }),
+		},
+		"bookName": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.String),
+			Resolve: bookField(func(b db.Book) any { return b.BookName }),
+		},
+		"isbn": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "ISBN-13, or ISBN-10 with its leading zeros restored",
+			Resolve:     bookField(func(b db.Book) any { return isbn.FromInt(b.ISBN) }),
+		},
+		"year": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Year of publication, null when unknown",
+			Resolve: bookField(func(b db.Book) any {
+				if b.Year == 0 {
+					return nil
+				}
+				return b.Year
+			}),
+		},
+		"createdAt": &graphql.Field{
+			Type:    graphql.DateTime,
+			Resolve: bookField(func(b db.Book) any { return b.CreatedAt }),
+		},
+		"updatedAt": &graphql.Field{
+			Type:    graphql.DateTime,
+			Resolve: bookField(func(b db.Book) any { return b.UpdatedAt }),
+		},
+	},
+})
+
+var authorType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Author",
+	Description: "Someone credited on at least one book",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(author).name, nil
+			},
+		},
+		"books": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+			Description: "Books credited to exactly this name, loaded in one query for all authors in the response",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return loaderFrom(p.Context).booksBy(p.Source.(author).name), nil
+			},
+		},
+	},
+})
+
+var bookPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "BookPage",
+	Fields: graphql.Fields{
+		"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType)))},
+		"totalCount":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var bookFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "BookFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"search":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Text contained in the title or author"},
+		"author":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Exact author name"},
+		"isbn":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"yearFrom": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"yearTo":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+var addBookInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "AddBookInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"bookName": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"author":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"isbn":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"year":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+var bookFieldType = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "BookField",
+	Description: "A field editBook can change",
+	Values: graphql.EnumValueConfigMap{
+		"BOOK_NAME": &graphql.EnumValueConfig{Value: "Book_name"},
+		"AUTHOR":    &graphql.EnumValueConfig{Value: "Author"},
+		"ISBN":      &graphql.EnumValueConfig{Value: "ISBN"},
+		"YEAR":      &graphql.EnumValueConfig{Value: "Year"},
+	},
+})
+
+var pageArgs = graphql.FieldConfigArgument{
+	"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "Page size, at most 100"},
+	"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+}
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"book": &graphql.Field{
+			Type: bookType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: resolveBook,
+		},
+		"books": &graphql.Field{
+			Type: graphql.NewNonNull(bookPageType),
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: bookFilterType},
+				"first":  pageArgs["first"],
+				"offset": pageArgs["offset"],
+			},
+			Resolve: resolveBooks,
+		},
+		"authors": &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(authorType))),
+			Args:    pageArgs,
+			Resolve: resolveAuthors,
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"addBook": &graphql.Field{
+			Type:        graphql.NewNonNull(bookType),
+			Description: "Adds a book, like POST /v1/books/add",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(addBookInputType)},
+			},
+			Resolve: resolveAddBook,
+		},
+		"editBook": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Changes one field of the books with a title, like PATCH /v1/books/edit",
+			Args: graphql.FieldConfigArgument{
+				"title": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"field": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookFieldType)},
+				"value": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolveEditBook,
+		},
+		"deleteBook": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Deletes the books with a title, like DELETE /v1/books/delete",
+			Args: graphql.FieldConfigArgument{
+				"title": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolveDeleteBook,
+		},
+	},
+})
+
+// Schema is the GraphQL schema served at /graphql
+var Schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	// added here rather than in the literal, which would make bookType and
+	// authorType refer to each other while being initialized
+	bookType.AddFieldConfig("author", &graphql.Field{
+		Type:    graphql.NewNonNull(authorType),
+		Resolve: bookField(func(b db.Book) any { return author{name: b.Author} }),
+	})
+	s, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType, Mutation: mutationType})
+	if err != nil {
+		panic("gql: building schema: " + err.Error())
+	}
+	return s
+}
+
+// bookField resolves a field of the Book type from its db row
+func bookField(get func(db.Book) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(db.Book)), nil
+	}
+}
+
+func resolveBook(p graphql.ResolveParams) (any, error) {
+	id, err := strconv.ParseInt(p.Args["id"].(string), 10, 64)
+	if err != nil {
+		return nil, invalid("id", "must be a book id")
+	}
+	book, err := db.GetBook(p.Context, id)
+	if err == db.ErrNotFound {
+		// a missing book is a null result rather than an error
+		return nil, nil
+	}
+	if err != nil {
+		return nil, serverError(p.Context, err)
+	}
+	return book, nil
+}
+
+func resolveBooks(p graphql.ResolveParams) (any, error) {
+	first, offset, err := page(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	var filter db.BookFilter
+	if f, ok := p.Args["filter"].(map[string]any); ok {
+		filter.Search, _ = f["search"].(string)
+		filter.Author, _ = f["author"].(string)
+		filter.YearFrom, _ = f["yearFrom"].(int)
+		filter.YearTo, _ = f["yearTo"].(int)
+		if code, ok := f["isbn"].(string); ok {
+			if filter.ISBN, err = strconv.Atoi(isbn.Normalize(code)); err != nil {
+				return nil, invalid("filter.isbn", "must be an ISBN of digits only")
+			}
+		}
+	}
+
+	books, total, err := db.FilterBooks(p.Context, filter, first, offset)
+	if err != nil {
+		return nil, serverError(p.Context, err)
+	}
+	return map[string]any{
+		"items":       books,
+		"totalCount":  total,
+		"hasNextPage": offset+len(books) < total,
+	}, nil
+}
+
+func resolveAuthors(p graphql.ResolveParams) (any, error) {
+	first, offset, err := page(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	names, err := db.ListAuthors(p.Context, first, offset)
+	if err != nil {
+		return nil, serverError(p.Context, err)
+	}
+	authors := make([]author, len(names))
+	for i, name := range names {
+		authors[i] = author{name: name}
+	}
+	return authors, nil
+}
+
+func page(args map[string]any) (first, offset int, err error) {
+	first, _ = args["first"].(int)
+	offset, _ = args["offset"].(int)
+	if first < 1 || first > maxPageSize {
+		return 0, 0, invalid("first", "must be between 1 and "+strconv.Itoa(maxPageSize))
+	}
+	if offset < 0 {
+		return 0, 0, invalid("offset", "must not be negative")
+	}
+	return first, offset, nil
+}
+
+func resolveAddBook(p graphql.ResolveParams) (any, error) {
+	in := p.Args["input"].(map[string]any)
+	req := models.CreateBookRequest{
+		BookName: in["bookName"].(string),
+		Author:   in["author"].(string),
+	}
+	req.Year, _ = in["year"].(int)
+	code := isbn.Normalize(in["isbn"].(string))
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return nil, invalid("input.isbn", "must be an ISBN of digits only")
+	}
+	req.ISBN = n
+	if fields := validation.Struct(req); fields != nil {
+		return nil, invalidFields(prefixed("input.", fields))
+	}
+
+	book, err := db.CreateBook(p.Context, db.NewBook(req))
+	if err != nil {
+		return nil, serverError(p.Context, err)
+	}
+	return book, nil
+}
+
+func resolveEditBook(p graphql.ResolveParams) (any, error) {
+	req := models.EditRequest{
+		Title: p.Args["title"].(string),
+		Field: p.Args["field"].(string),
+		Value: p.Args["value"].(string),
+	}
+	if fields := validation.Struct(req); fields != nil {
+		return nil, invalidFields(fields)
+	}
+	value, fields := validation.EditValue(req)
+	if fields != nil {
+		return nil, invalidFields(fields)
+	}
+	if err := db.UpdateField(p.Context, req.Title, req.Field, value); err != nil {
+		return nil, notFoundOr(p.Context, err)
+	}
+	return true, nil
+}
+
+func resolveDeleteBook(p graphql.ResolveParams) (any, error) {
+	req := models.DeleteRequest{Title: p.Args["title"].(string)}
+	if fields := validation.Struct(req); fields != nil {
+		return nil, invalidFields(fields)
+	}
+	if err := db.DeleteByTitle(p.Context, req.Title); err != nil {
+		return nil, notFoundOr(p.Context, err)
+	}
+	return true, nil
+}
+
+func notFoundOr(ctx context.Context, err error) error {
+	if err == db.ErrNotFound {
+		return &codedError{message: "Book not found", code: "not_found"}
+	}
+	return serverError(ctx, err)
+}
+
+func prefixed(prefix string, fields map[string]string) map[string]string {
+	out := make(map[string]string, len(fields))
+	for name, msg := range fields {
+		out[prefix+name] = msg
+	}
+	return out
+}
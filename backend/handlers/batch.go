@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/validation"
+	"net/http"
+)
+
+// opError is a failed batch operation with the status it maps to
+type opError struct {
+	status int
+	err    error
+}
+
+func (e *opError) Error() string { return e.err.Error() }
+func (e *opError) Unwrap() error { return e.err }
+
+func badOp(format string, args ...any) *opError {
+	return &opError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// errBatchFailed aborts the transaction of an all-or-nothing batch
+var errBatchFailed = errors.New("batch failed")
+
+func BatchBooks(c *gin.Context) {
+	var req models.BatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := models.BatchResponse{Results: make([]models.BatchResult, 0, len(req.Operations))}
+	failedStatus := 0
+
+	err := db.WithTx(ctx, func(tx *db.Tx) error {
+		for i, op := range req.Operations {
+			var id int64
+			var err error
+			if req.ContinueOnError {
+				err = tx.Savepoint(ctx, func() error {
+					var opErr error
+					id, opErr = applyOperation(ctx, tx, op)
+					return opErr
+				})
+			} else {
+				id, err = applyOperation(ctx, tx, op)
+			}
+
+			result := models.BatchResult{Index: i, Op: op.Op, Status: http.StatusOK, ID: id}
+			if op.Op == "create" {
+				result.Status = http.StatusCreated
+			}
+			if err != nil {
+				result.Status = operationStatus(err)
+				result.Error = err.Error()
+			}
+			resp.Results = append(resp.Results, result)
+
+			if err != nil && !req.ContinueOnError {
+				failedStatus = result.Status
+				resp.Error = fmt.Sprintf("Operation %d (%s) failed: %v", i, op.Op, err)
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBatchFailed):
+		c.IndentedJSON(failedStatus, resp)
+	case err != nil:
+		serverError(c, err)
+	default:
+		resp.Committed = true
+		c.IndentedJSON(http.StatusOK, resp)
+	}
+}
+
+// applyOperation runs op and returns the id of the book it touched
+func applyOperation(ctx context.Context, tx *db.Tx, op models.BatchOperation) (int64, error) {
+	if fields := validation.Struct(op); fields != nil {
+		return op.ID, badOp("%s", validation.Summary(fields))
+	}
+	switch op.Op {
+	case "create":
+		if op.BookName == nil || op.Author == nil || op.ISBN == nil {
+			return 0, badOp("create needs book_name, author and isbn")
+		}
+		req := models.CreateBookRequest{BookName: *op.BookName, Author: *op.Author, ISBN: *op.ISBN}
+		if op.Year != nil {
+			req.Year = *op.Year
+		}
+		return tx.AddBook(ctx, db.NewBook(req))
+
+	case "update":
+		if op.ID == 0 {
+			return 0, badOp("update needs an id")
+		}
+		if op.Update().Empty() {
+			return op.ID, badOp("update needs at least one of book_name, author, isbn or year")
+		}
+		book, err := tx.GetBook(ctx, op.ID)
+		if err != nil {
+			return op.ID, err
+		}
+		book.Apply(op.Update())
+		return op.ID, tx.UpdateBook(ctx, book)
+
+	case "delete":
+		if op.ID == 0 {
+			return 0, badOp("delete needs an id")
+		}
+		return op.ID, tx.DeleteBook(ctx, op.ID)
+	}
+	return 0, badOp("unknown op %q, expected create, update or delete", op.Op)
+}
+
+func operationStatus(err error) int {
+	var opErr *opError
+	switch {
+	case errors.As(err, &opErr):
+		return opErr.status
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -4,112 +4,169 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kushalpraja/library-api/db"
 	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/validation"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func GetBooks(c *gin.Context) {
 	// select all books from the library table
-	rows, err := db.DB.Query("SELECT Book_name, Author, ISBN FROM library")
+	books, err := db.ListBooks(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	defer rows.Close()
-	var books []models.Book
-	for rows.Next() {
-		var book models.Book
-		if err := rows.Scan(&book.BookName, &book.Author, &book.ISBN); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		books = append(books, book)
+	c.IndentedJSON(http.StatusOK, db.Models(books))
+}
+
+func SearchBooks(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	books, err := db.SearchBooks(c.Request.Context(), q)
+	if err != nil {
+		serverError(c, err)
+		return
 	}
-	c.IndentedJSON(http.StatusOK, books)
+	c.IndentedJSON(http.StatusOK, db.Models(books))
 }
 
-func AddBook(c *gin.Context) {
-	var book models.Book
-	if err := c.ShouldBindJSON(&book); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func GetBook(c *gin.Context) {
+	book, ok := bookFromParam(c)
+	if !ok {
 		return
 	}
-	_, err := db.DB.Exec("INSERT INTO library (Book_name, Author, ISBN) VALUES (?, ?, ?)",
-		book.BookName, book.Author, book.ISBN)
+	c.IndentedJSON(http.StatusOK, book.Model())
+}
+
+func bookFromParam(c *gin.Context) (db.Book, bool) {
+	id, ok := idFromParam(c)
+	if !ok {
+		return db.Book{}, false
+	}
+	book, err := db.GetBook(c.Request.Context(), id)
+	if err == db.ErrNotFound {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return db.Book{}, false
+	}
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
+		return db.Book{}, false
+	}
+	return book, true
+}
+
+func idFromParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid book id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func AddBook(c *gin.Context) {
+	var req models.CreateBookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if _, err := db.AddBook(c.Request.Context(), db.NewBook(req)); err != nil {
+		serverError(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusCreated, gin.H{"message": "Book added successfully"})
 }
 
-func EditBook(c *gin.Context) {
-	var req struct {
-		Title string `json:"title"`
-		Field string `json:"field"`
-		Value string `json:"value"`
+// UpdateBook changes the fields set in the body and responds with the book
+func UpdateBook(c *gin.Context) {
+	id, ok := idFromParam(c)
+	if !ok {
+		return
 	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.UpdateBookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Empty() {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "At least one of book_name, author, isbn or year is required"})
 		return
 	}
 
-	var query string
-	var value any
+	book, err := db.PatchBook(c.Request.Context(), id, req)
+	if err == db.ErrNotFound {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, book.Model())
+}
 
-	switch req.Field {
-	case "Book_name", "Author":
-		query = "UPDATE library SET " + req.Field + " = ? WHERE Book_name = ?"
-		value = req.Value
-	case "ISBN":
-		intVal, err := strconv.Atoi(req.Value)
-		if err != nil {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
-			return
-		}
-		query = "UPDATE library SET ISBN = ? WHERE Book_name = ?"
-		value = intVal
-	default:
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid field"})
+func EditBook(c *gin.Context) {
+	var req models.EditRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	result, err := db.DB.Exec(query, value, req.Title)
-	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	value, fields := validation.EditValue(req)
+	if fields != nil {
+		invalidRequest(c, fields)
 		return
 	}
 
-	affected, _ := result.RowsAffected()
-	if affected == 0 {
+	err := db.UpdateField(c.Request.Context(), req.Title, req.Field, value)
+	if err == db.ErrNotFound {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
 
 	c.IndentedJSON(http.StatusOK, gin.H{"message": "Book updated"})
 }
 
 func DeleteBook(c *gin.Context) {
-	var req struct {
-		Title string `json:"title"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.DeleteRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	result, err := db.DB.Exec("DELETE FROM library WHERE Book_name = ?", req.Title)
+	err := db.DeleteByTitle(c.Request.Context(), req.Title)
+	if err == db.ErrNotFound {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
 
-	affected, _ := result.RowsAffected()
-	if affected == 0 {
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Book deleted"})
+}
+
+func DeleteBookByID(c *gin.Context) {
+	id, ok := idFromParam(c)
+	if !ok {
+		return
+	}
+
+	err := db.DeleteBook(c.Request.Context(), id)
+	if err == db.ErrNotFound {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
 
 	c.IndentedJSON(http.StatusOK, gin.H{"message": "Book deleted"})
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/validation"
+	"log/slog"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status (from nginx) logged
+// when the client went away before we could answer
+const StatusClientClosedRequest = 499
+
+// bindJSON decodes and validates the request body into v. When that fails
+// it responds 400, naming each invalid field, and returns false.
+func bindJSON(c *gin.Context, v any) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+	if fields := validation.Fields(err); fields != nil {
+		invalidRequest(c, fields)
+		return false
+	}
+	c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
+
+// invalidRequest responds 400 with a message for each invalid field
+func invalidRequest(c *gin.Context, fields map[string]string) {
+	c.IndentedJSON(http.StatusBadRequest, gin.H{
+		"error":  "Invalid request: " + validation.Summary(fields),
+		"code":   "validation_failed",
+		"fields": fields,
+	})
+}
+
+// serverError responds to a failure the client can't fix. Work cut short by
+// the client disconnecting gets a 499 and a query that ran out of time a
+// 503, both with a code so callers can tell them from real errors.
+func serverError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	switch {
+	case errors.Is(err, context.Canceled):
+		slog.InfoContext(ctx, "request canceled by client", "route", c.FullPath())
+		c.IndentedJSON(StatusClientClosedRequest, gin.H{"error": "Request canceled", "code": "canceled"})
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.WarnContext(ctx, "request timed out", "route", c.FullPath(), "error", err)
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": "Database query timed out", "code": "timeout"})
+	default:
+		slog.ErrorContext(ctx, "request failed", "route", c.FullPath(), "error", err)
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// eventsHeartbeat is how often an idle stream gets a comment line, so
+	// proxies don't close it and dead clients are noticed
+	eventsHeartbeat = 15 * time.Second
+	eventsBatch     = 100
+)
+
+// Events streams the change events as Server-Sent Events. A client
+// resuming after a disconnect sends the id of the last event it saw in
+// Last-Event-ID, as browsers do on their own, or in the after parameter.
+// Without either the stream starts with the next change. When events
+// after the resume id have been purged the client gets a 410 rather than
+// a stream with a silent gap.
+func Events(c *gin.Context) {
+	ctx := c.Request.Context()
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("after")
+	}
+	var after int64
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be an event id"})
+			return
+		}
+		after = id
+		first, err := db.FirstEventID(ctx)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+		if after < first-1 {
+			c.IndentedJSON(http.StatusGone, gin.H{
+				"error": "Events after " + strconv.FormatInt(after, 10) + " have been purged, reload and reconnect without Last-Event-ID",
+				"code":  "events_purged",
+			})
+			return
+		}
+	} else {
+		latest, err := db.LatestEventID(ctx)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+		after = latest
+	}
+
+	// the stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(ctx, "can't lift write deadline for event stream", "error", err)
+	}
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// ask nginx not to buffer the stream
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		// taken before reading so a commit in between isn't missed
+		recorded := db.EventsRecorded()
+		events, err := db.EventsAfter(ctx, after, eventsBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "event stream failed", "error", err)
+			}
+			return
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				slog.ErrorContext(ctx, "event stream failed", "error", err)
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			after = event.ID
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+		}
+		if len(events) == eventsBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-streamsDone:
+			return
+		case <-recorded:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
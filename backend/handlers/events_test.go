@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Main(m)
+}
+
+func eventServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dbtest.Open(t)
+
+	r := gin.New()
+	r.GET("/v1/events", Events)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// openEvents connects to the stream, resuming after lastEventID unless
+// it is empty
+func openEvents(t *testing.T, srv *httptest.Server, lastEventID string) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// nextIDs reads the ids of the next n events from the stream
+func nextIDs(t *testing.T, stream *bufio.Reader, n int) []string {
+	t.Helper()
+	var ids []string
+	for len(ids) < n {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream after ids %v: %v", ids, err)
+		}
+		if id, ok := strings.CutPrefix(strings.TrimSpace(line), "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestEventsResumeFromLastEventID(t *testing.T) {
+	srv := eventServer(t)
+	dbtest.AddBooks(t, "Dune", "Emma", "Ulysses")
+
+	resp := openEvents(t, srv, "1")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %s with Content-Type %q", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	stream := bufio.NewReader(resp.Body)
+	if got := nextIDs(t, stream, 2); strings.Join(got, ",") != "2,3" {
+		t.Fatalf("resumed with events %v, want [2 3]", got)
+	}
+
+	// then changes are pushed as they are committed
+	dbtest.AddBooks(t, "Persuasion")
+	if got := nextIDs(t, stream, 1); got[0] != "4" {
+		t.Fatalf("pushed event %v, want [4]", got)
+	}
+}
+
+func TestEventsWithoutLastEventIDStartAtNextChange(t *testing.T) {
+	srv := eventServer(t)
+	dbtest.AddBooks(t, "Dune", "Emma")
+
+	stream := bufio.NewReader(openEvents(t, srv, "").Body)
+	// the retry line is sent once the stream is set up
+	if line, _ := stream.ReadString('\n'); !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("stream starts with %q", line)
+	}
+	dbtest.AddBooks(t, "Ulysses")
+	if got := nextIDs(t, stream, 1); got[0] != "3" {
+		t.Fatalf("first event %v, want [3]", got)
+	}
+}
+
+func TestEventsGoneAfterPurge(t *testing.T) {
+	srv := eventServer(t)
+	ctx := context.Background()
+	hook, err := db.AddWebhook(ctx, "https://example.com/hook", "0123456789abcdef0123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.AddBooks(t, "Dune", "Emma", "Ulysses")
+
+	// the webhook hasn't been sent the events, so they are kept
+	if n, err := db.PurgeEvents(ctx, time.Now().Add(time.Hour)); err != nil || n != 0 {
+		t.Fatalf("purged %d events (%v) a webhook still needs", n, err)
+	}
+	if err := db.AdvanceWebhook(ctx, hook.ID, 2, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.PurgeEvents(ctx, time.Now().Add(time.Hour)); err != nil || n != 2 {
+		t.Fatalf("purged %d events (%v), want the 2 the webhook was sent", n, err)
+	}
+
+	for lastEventID, want := range map[string]int{
+		"0": http.StatusGone,
+		"1": http.StatusGone,
+		"2": http.StatusOK,
+		"3": http.StatusOK,
+	} {
+		if resp := openEvents(t, srv, lastEventID); resp.StatusCode != want {
+			t.Errorf("resuming after %s: got %s, want %d", lastEventID, resp.Status, want)
+		}
+	}
+
+	// with every event purged the next id still tells a gap apart
+	if err := db.DeleteWebhook(ctx, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.PurgeEvents(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if resp := openEvents(t, srv, "2"); resp.StatusCode != http.StatusGone {
+		t.Errorf("resuming after 2 with no events kept: got %s, want 410", resp.Status)
+	}
+	if resp := openEvents(t, srv, "3"); resp.StatusCode != http.StatusOK {
+		t.Errorf("resuming after 3 with no events kept: got %s, want 200", resp.Status)
+	}
+}
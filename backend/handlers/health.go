@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/version"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReadinessTimeout bounds the database checks made by Readyz
+var ReadinessTimeout = 2 * time.Second
+
+var (
+	shuttingDown atomic.Bool
+	// streamsDone is closed on shutdown to end long-lived responses, which
+	// would otherwise hold up the drain until its deadline
+	streamsDone = make(chan struct{})
+	stopStreams sync.Once
+)
+
+// SetShuttingDown makes Readyz fail so load balancers stop sending traffic
+// while in-flight requests drain, and closes open event streams so their
+// clients reconnect elsewhere
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+	stopStreams.Do(func() { close(streamsDone) })
+}
+
+// Healthz reports that the process is up and serving
+func Healthz(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the server can take traffic: it isn't shutting
+// down, the database answers and its schema is current
+func Readyz(c *gin.Context) {
+	if shuttingDown.Load() {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ReadinessTimeout)
+	defer cancel()
+
+	checks := gin.H{"database": "ok", "migrations": "ok"}
+	ready := true
+	if err := db.DB.PingContext(ctx); err != nil {
+		checks["database"] = err.Error()
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if current, err := db.CurrentVersion(ctx); err != nil {
+		checks["migrations"] = err.Error()
+		ready = false
+	} else if current != db.SchemaVersion {
+		checks["migrations"] = fmt.Sprintf("schema at version %d, expected %d", current, db.SchemaVersion)
+		ready = false
+	}
+
+	if !ready {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
+
+// Version reports the build and the schema version it expects
+func Version(c *gin.Context) {
+	info := version.Get()
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"commit":         info.Commit,
+		"build_time":     info.BuildTime,
+		"go_version":     info.GoVersion,
+		"schema_version": db.SchemaVersion,
+	})
+}
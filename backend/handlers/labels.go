@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/labels"
+	"github.com/kushalpraja/library-api/middleware"
+	"github.com/kushalpraja/library-api/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PublicBaseURL is the externally visible address encoded into QR codes.
+// When empty it is derived from the incoming request.
+var PublicBaseURL string
+
+func GetBarcode(c *gin.Context) {
+	book, ok := bookFromParam(c)
+	if !ok {
+		return
+	}
+
+	var symbol labels.Symbol
+	var err error
+	switch c.DefaultQuery("symbology", "code128") {
+	case "code128":
+		symbol, err = labels.Code128(isbn.FromInt(book.ISBN))
+	case "ean13":
+		symbol, err = labels.EAN13(isbn.FromInt(book.ISBN))
+		if errors.Is(err, isbn.ErrInvalid) {
+			c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"error": "Book ISBN can't be encoded as EAN-13"})
+			return
+		}
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid symbology"})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	writeSymbol(c, symbol, 2)
+}
+
+func GetQRCode(c *gin.Context) {
+	book, ok := bookFromParam(c)
+	if !ok {
+		return
+	}
+
+	symbol, err := labels.QR(bookURL(c, book.ID))
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	writeSymbol(c, symbol, 4)
+}
+
+func PrintLabels(c *gin.Context) {
+	var req models.LabelsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Format == "" {
+		req.Format = "pdf"
+	}
+	if req.Format != "pdf" && req.Format != "svg" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+		return
+	}
+
+	sheet := make([]labels.Label, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		book, err := db.GetBook(c.Request.Context(), id)
+		if err == db.ErrNotFound {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Book %d not found", id)})
+			return
+		}
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		label, err := bookLabel(c, book)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+		sheet = append(sheet, label)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType := "application/pdf"
+	if req.Format == "svg" {
+		contentType = "image/svg+xml"
+		err = labels.WriteSheetSVG(&buf, sheet)
+	} else {
+		err = labels.WriteSheetPDF(&buf, sheet)
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "inline; filename=labels."+req.Format)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// bookLabel builds a label, preferring the EAN-13 cover barcode and falling
+// back to Code 128 for ISBNs that don't convert
+func bookLabel(c *gin.Context, book db.Book) (labels.Label, error) {
+	code := isbn.FromInt(book.ISBN)
+	barcode, err := labels.EAN13(code)
+	if err != nil {
+		barcode, err = labels.Code128(code)
+		if err != nil {
+			return labels.Label{}, err
+		}
+	}
+	qr, err := labels.QR(bookURL(c, book.ID))
+	if err != nil {
+		return labels.Label{}, err
+	}
+	return labels.Label{Title: book.BookName, ISBN: code, Barcode: barcode, QR: qr}, nil
+}
+
+// writeSymbol responds with the symbol in the requested format, png by default
+func writeSymbol(c *gin.Context, symbol labels.Symbol, defaultScale int) {
+	scale, err := strconv.Atoi(c.DefaultQuery("scale", strconv.Itoa(defaultScale)))
+	if err != nil || scale < 1 || scale > 20 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid scale"})
+		return
+	}
+
+	var buf bytes.Buffer
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		err = labels.WritePNG(&buf, symbol, scale)
+		if err == nil {
+			c.Data(http.StatusOK, "image/png", buf.Bytes())
+		}
+	case "svg":
+		err = labels.WriteSVG(&buf, symbol, scale)
+		if err == nil {
+			c.Data(http.StatusOK, "image/svg+xml", buf.Bytes())
+		}
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+	}
+}
+
+func bookURL(c *gin.Context, id int64) string {
+	base := PublicBaseURL
+	if base == "" {
+		base = middleware.Scheme(c) + "://" + c.Request.Host
+	}
+	return strings.TrimRight(base, "/") + "/v1/books/" + strconv.FormatInt(id, 10)
+}
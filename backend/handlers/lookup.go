@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/metadata"
+	"github.com/kushalpraja/library-api/models"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yearPattern finds the year in publish dates such as "May 4, 2016"
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+func LookupBook(c *gin.Context) {
+	var req models.LookupRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	code := isbn.Normalize(req.ISBN)
+
+	if metadata.Default == nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"error": "Metadata lookup is not configured"})
+		return
+	}
+
+	meta, err := metadata.Default.Lookup(c.Request.Context(), code)
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+		slog.WarnContext(c.Request.Context(), "metadata lookup failed", "isbn", code, "error", err)
+	}
+	switch {
+	case errors.Is(err, metadata.ErrNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No metadata found for ISBN"})
+		return
+	case errors.Is(err, context.DeadlineExceeded) || isTimeout(err):
+		c.IndentedJSON(http.StatusGatewayTimeout, gin.H{"error": "Metadata provider timed out"})
+		return
+	case err != nil:
+		c.IndentedJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	// an ISBN-10 ending in X can't be stored in the integer column, leave it for the user
+	isbnValue, _ := strconv.Atoi(code)
+	c.IndentedJSON(http.StatusOK, models.LookupResponse{
+		Book: models.CreateBookRequest{
+			BookName: meta.Title,
+			Author:   strings.Join(meta.Authors, ", "),
+			ISBN:     isbnValue,
+			Year:     publishYear(meta.PublishDate),
+		},
+		Metadata: meta,
+	})
+}
+
+// publishYear picks the year out of a provider's free-form publish date,
+// or returns 0 when there isn't one
+func publishYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
+
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
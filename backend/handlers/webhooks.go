@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/webhooks"
+	"net/http"
+	"strconv"
+)
+
+// CreateWebhook registers a webhook for the change events. The response
+// is the only one that includes the secret, generated unless given. URLs
+// on the server's own network are refused unless WEBHOOK_ALLOW_PRIVATE.
+func CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := webhooks.CheckURL(c.Request.Context(), req.URL); err != nil {
+		invalidRequest(c, map[string]string{"url": "must not point to a private, loopback or link-local address"})
+		return
+	}
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		req.Secret = hex.EncodeToString(b)
+	}
+
+	hook, err := db.AddWebhook(c.Request.Context(), req.URL, req.Secret)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, hook)
+}
+
+// ListWebhooks returns the registered webhooks and how their deliveries
+// are going, without their secrets
+func ListWebhooks(c *gin.Context) {
+	hooks, err := db.ListWebhooks(c.Request.Context())
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.IndentedJSON(http.StatusOK, hooks)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+	err = db.DeleteWebhook(c.Request.Context(), id)
+	if err == db.ErrNotFound {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
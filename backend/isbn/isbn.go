@@ -0,0 +1,100 @@
+package isbn
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned when a value is not a well-formed ISBN-10 or ISBN-13
+var ErrInvalid = errors.New("invalid isbn")
+
+// Normalize strips the separators people usually type into an ISBN
+// and upper-cases a trailing X check digit
+func Normalize(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return strings.NewReplacer("-", "", " ", "").Replace(s)
+}
+
+// FromInt formats an ISBN stored in the integer column. Values longer than
+// ten digits are treated as ISBN-13, anything shorter as an ISBN-10 whose
+// leading zeros were lost when it was stored.
+func FromInt(n int) string {
+	s := strconv.Itoa(n)
+	if len(s) > 10 {
+		return strings.Repeat("0", max(13-len(s), 0)) + s
+	}
+	return strings.Repeat("0", 10-len(s)) + s
+}
+
+// Valid reports whether s is an ISBN-10 or ISBN-13 with a correct check digit
+func Valid(s string) bool {
+	s = Normalize(s)
+	switch len(s) {
+	case 10:
+		return valid10(s)
+	case 13:
+		return valid13(s)
+	}
+	return false
+}
+
+// To13 converts an ISBN-10 or ISBN-13 into its ISBN-13 (EAN-13) form
+func To13(s string) (string, error) {
+	s = Normalize(s)
+	switch {
+	case len(s) == 13 && valid13(s):
+		return s, nil
+	case len(s) == 10 && valid10(s):
+		body := "978" + s[:9]
+		return body + checkDigit13(body), nil
+	}
+	return "", ErrInvalid
+}
+
+// CheckDigit10 returns the check digit for the first nine digits of an ISBN-10
+func CheckDigit10(body string) string {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return "X"
+	}
+	return strconv.Itoa(check)
+}
+
+func checkDigit13(body string) string {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+func valid10(s string) bool {
+	for i := 0; i < 9; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	last := s[9]
+	if last != 'X' && (last < '0' || last > '9') {
+		return false
+	}
+	return CheckDigit10(s[:9]) == string(last)
+}
+
+func valid13(s string) bool {
+	for i := 0; i < 13; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return checkDigit13(s[:12]) == s[12:]
+}
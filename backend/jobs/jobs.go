@@ -0,0 +1,45 @@
+// Package jobs runs periodic background work alongside the server.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs fn every interval until the returned stop function is called.
+// stop cancels the context passed to a run in progress and waits for it to
+// return, or for ctx to expire, so it can be registered as a shutdown hook.
+func Every(name string, interval time.Duration, fn func(context.Context) error) (stop func(context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			start := time.Now()
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Background job failed", "job", name, "error", err)
+				continue
+			}
+			slog.Debug("Background job finished", "job", name, "duration_ms", time.Since(start).Milliseconds())
+		}
+	}()
+
+	return func(wait context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-wait.Done():
+			return wait.Err()
+		}
+	}
+}
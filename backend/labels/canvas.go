@@ -0,0 +1,111 @@
+package labels
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// canvas is the drawing surface shared by the PNG, SVG and PDF renderers.
+// Coordinates start at the top left corner and grow right and down.
+type canvas interface {
+	Rect(x, y, w, h float64)
+	Text(x, y, size float64, text string)
+}
+
+// drawSymbol fills the box at x, y with the dark modules of s. Linear
+// symbols are stretched to the full box, matrix symbols stay square.
+func drawSymbol(c canvas, s Symbol, x, y, w, h float64) {
+	if !s.Is2D() {
+		module := w / float64(s.Width())
+		for _, r := range darkRuns(s, 0) {
+			c.Rect(x+float64(r.start)*module, y, float64(r.length)*module, h)
+		}
+		return
+	}
+
+	module := min(w, h) / float64(s.Width())
+	for row := 0; row < s.Height(); row++ {
+		for _, r := range darkRuns(s, row) {
+			c.Rect(x+float64(r.start)*module, y+float64(row)*module, float64(r.length)*module, module)
+		}
+	}
+}
+
+type run struct {
+	start, length int
+}
+
+// darkRuns returns the runs of dark modules in a row, so adjacent bars
+// are drawn as a single rectangle
+func darkRuns(s Symbol, row int) []run {
+	var runs []run
+	start := -1
+	for x := 0; x <= s.Width(); x++ {
+		dark := x < s.Width() && s.Dark(x, row)
+		switch {
+		case dark && start < 0:
+			start = x
+		case !dark && start >= 0:
+			runs = append(runs, run{start: start, length: x - start})
+			start = -1
+		}
+	}
+	return runs
+}
+
+// rasterCanvas draws onto a grayscale image; text is not supported
+type rasterCanvas struct {
+	img *image.Gray
+}
+
+func newRasterCanvas(w, h int) *rasterCanvas {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	return &rasterCanvas{img: img}
+}
+
+func (r *rasterCanvas) Rect(x, y, w, h float64) {
+	x0, y0 := int(x+0.5), int(y+0.5)
+	x1, y1 := int(x+w+0.5), int(y+h+0.5)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			r.img.SetGray(px, py, color.Gray{Y: 0})
+		}
+	}
+}
+
+func (r *rasterCanvas) Text(x, y, size float64, text string) {}
+
+// svgCanvas collects SVG elements for a document of the given size
+type svgCanvas struct {
+	width, height float64
+	body          strings.Builder
+}
+
+func (s *svgCanvas) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&s.body, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f"/>`+"\n", x, y, w, h)
+}
+
+func (s *svgCanvas) Text(x, y, size float64, text string) {
+	fmt.Fprintf(&s.body, `<text x="%.2f" y="%.2f" font-size="%.1f">%s</text>`+"\n", x, y, size, escapeXML(text))
+}
+
+func (s *svgCanvas) WriteTo(w io.Writer) (int64, error) {
+	n, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%.2f" height="%.2f" viewBox="0 0 %.2f %.2f">
+<rect width="100%%" height="100%%" fill="#ffffff"/>
+<g fill="#000000" font-family="Helvetica, Arial, sans-serif">
+%s</g>
+</svg>
+`, s.width, s.height, s.width, s.height, s.body.String())
+	return int64(n), err
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
@@ -0,0 +1,96 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pdf is a minimal PDF 1.4 writer that supports filled rectangles and
+// Helvetica text, which is all a label sheet needs
+type pdf struct {
+	width, height float64
+	pages         []*pdfCanvas
+}
+
+func newPDF(width, height float64) *pdf {
+	return &pdf{width: width, height: height}
+}
+
+func (p *pdf) AddPage() *pdfCanvas {
+	page := &pdfCanvas{height: p.height}
+	p.pages = append(p.pages, page)
+	return page
+}
+
+// pdfCanvas records the content stream of one page. PDF puts the origin at
+// the bottom left, so y coordinates are flipped on the way in.
+type pdfCanvas struct {
+	height  float64
+	content bytes.Buffer
+}
+
+func (c *pdfCanvas) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&c.content, "%.2f %.2f %.2f %.2f re f\n", x, c.height-y-h, w, h)
+}
+
+func (c *pdfCanvas) Text(x, y, size float64, text string) {
+	fmt.Fprintf(&c.content, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, c.height-y, escapePDF(text))
+}
+
+func (p *pdf) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// objects 1-3 are the catalog, page tree and font; each page then
+	// takes two objects, the page itself followed by its content stream
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, page := range p.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			p.width, p.height, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escapePDF escapes a string literal and replaces characters the
+// standard Helvetica encoding can't show
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '…':
+			b.WriteString("...")
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
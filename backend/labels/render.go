@@ -0,0 +1,46 @@
+package labels
+
+import (
+	"image/png"
+	"io"
+)
+
+// linearBarHeight is the bar height of a linear symbol, in modules
+const linearBarHeight = 40
+
+// symbolSize returns the image size in modules of s including its quiet zone
+func symbolSize(s Symbol) (float64, float64) {
+	qz := s.quietZone()
+	if s.Is2D() {
+		side := float64(s.Width() + 2*qz)
+		return side, side
+	}
+	return float64(s.Width() + 2*qz), float64(linearBarHeight + 2*qz)
+}
+
+func drawStandalone(c canvas, s Symbol, scale float64) {
+	qz := float64(s.quietZone()) * scale
+	if s.Is2D() {
+		side := float64(s.Width()) * scale
+		drawSymbol(c, s, qz, qz, side, side)
+		return
+	}
+	drawSymbol(c, s, qz, qz, float64(s.Width())*scale, linearBarHeight*scale)
+}
+
+// WritePNG renders s as a PNG image using scale pixels per module
+func WritePNG(w io.Writer, s Symbol, scale int) error {
+	width, height := symbolSize(s)
+	c := newRasterCanvas(int(width)*scale, int(height)*scale)
+	drawStandalone(c, s, float64(scale))
+	return png.Encode(w, c.img)
+}
+
+// WriteSVG renders s as an SVG document using scale units per module
+func WriteSVG(w io.Writer, s Symbol, scale int) error {
+	width, height := symbolSize(s)
+	c := &svgCanvas{width: width * float64(scale), height: height * float64(scale)}
+	drawStandalone(c, s, float64(scale))
+	_, err := c.WriteTo(w)
+	return err
+}
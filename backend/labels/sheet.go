@@ -0,0 +1,82 @@
+package labels
+
+import (
+	"io"
+)
+
+// Label is one printable shelf label for a book
+type Label struct {
+	Title   string
+	ISBN    string
+	Barcode Symbol
+	QR      Symbol
+}
+
+// Sheet layout in points on an A4 page
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 28.0
+	labelColumns = 2
+	labelRows    = 7
+	labelWidth   = (pageWidth - 2*pageMargin) / labelColumns
+	labelHeight  = (pageHeight - 2*pageMargin) / labelRows
+	labelPadding = 8.0
+	labelsOnPage = labelColumns * labelRows
+)
+
+// drawLabel lays out a single label with its top left corner at x, y:
+// title on top, the linear barcode and ISBN on the left and the QR code on the right
+func drawLabel(c canvas, l Label, x, y float64) {
+	qrSide := labelHeight - 2*labelPadding
+	textWidth := labelWidth - qrSide - 3*labelPadding
+
+	c.Text(x+labelPadding, y+labelPadding+9, 9, truncate(l.Title, 38))
+	drawSymbol(c, l.Barcode, x+labelPadding, y+labelPadding+16, textWidth, qrSide-34)
+	c.Text(x+labelPadding, y+labelHeight-labelPadding, 8, "ISBN "+l.ISBN)
+	drawSymbol(c, l.QR, x+labelWidth-labelPadding-qrSide, y+labelPadding, qrSide, qrSide)
+}
+
+// labelOrigin returns the top left corner of the i-th label on its page
+func labelOrigin(i int) (float64, float64) {
+	i %= labelsOnPage
+	column, row := i%labelColumns, i/labelColumns
+	return pageMargin + float64(column)*labelWidth, pageMargin + float64(row)*labelHeight
+}
+
+// WriteSheetSVG renders the labels as A4 pages stacked in one SVG document
+func WriteSheetSVG(w io.Writer, labels []Label) error {
+	pages := max((len(labels)+labelsOnPage-1)/labelsOnPage, 1)
+	c := &svgCanvas{width: pageWidth, height: pageHeight * float64(pages)}
+	for i, l := range labels {
+		x, y := labelOrigin(i)
+		drawLabel(c, l, x, y+float64(i/labelsOnPage)*pageHeight)
+	}
+	_, err := c.WriteTo(w)
+	return err
+}
+
+// WriteSheetPDF renders the labels as a multi-page A4 PDF
+func WriteSheetPDF(w io.Writer, labels []Label) error {
+	doc := newPDF(pageWidth, pageHeight)
+	var page *pdfCanvas
+	for i, l := range labels {
+		if i%labelsOnPage == 0 {
+			page = doc.AddPage()
+		}
+		x, y := labelOrigin(i)
+		drawLabel(page, l, x, y)
+	}
+	if page == nil {
+		doc.AddPage()
+	}
+	return doc.Write(w)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,77 @@
+package labels
+
+import (
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/kushalpraja/library-api/isbn"
+	"image/color"
+)
+
+// Symbol is an encoded barcode laid out as a grid of dark and light modules.
+// One-dimensional symbols have a single row.
+type Symbol struct {
+	code barcode.Barcode
+}
+
+// Code128 encodes content as a Code 128 barcode
+func Code128(content string) (Symbol, error) {
+	code, err := code128.Encode(content)
+	if err != nil {
+		return Symbol{}, err
+	}
+	return Symbol{code: code}, nil
+}
+
+// EAN13 encodes an ISBN-10 or ISBN-13 as the EAN-13 barcode printed on book covers
+func EAN13(value string) (Symbol, error) {
+	isbn13, err := isbn.To13(value)
+	if err != nil {
+		return Symbol{}, err
+	}
+	code, err := ean.Encode(isbn13)
+	if err != nil {
+		return Symbol{}, err
+	}
+	return Symbol{code: code}, nil
+}
+
+// QR encodes content as a QR code with medium error correction
+func QR(content string) (Symbol, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return Symbol{}, err
+	}
+	return Symbol{code: code}, nil
+}
+
+// Width is the number of modules across the symbol
+func (s Symbol) Width() int {
+	return s.code.Bounds().Dx()
+}
+
+// Height is the number of module rows, 1 for linear barcodes
+func (s Symbol) Height() int {
+	return s.code.Bounds().Dy()
+}
+
+// Is2D reports whether the symbol is a matrix code such as QR
+func (s Symbol) Is2D() bool {
+	return s.code.Metadata().Dimensions == 2
+}
+
+// Dark reports whether the module at x, y is printed
+func (s Symbol) Dark(x, y int) bool {
+	bounds := s.code.Bounds()
+	gray := color.GrayModel.Convert(s.code.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+	return gray.Y < 128
+}
+
+// quietZone is the blank margin, in modules, a scanner needs around the symbol
+func (s Symbol) quietZone() int {
+	if s.Is2D() {
+		return 4
+	}
+	return 10
+}
@@ -0,0 +1,6 @@
+// Package librarypb holds the LibraryService protobuf definition and the
+// code generated from it. Regenerate after changing library.proto; make
+// proto pins protoc and its plugins to the versions in the Makefile.
+package librarypb
+
+//go:generate make -C .. proto
@@ -1,14 +1,195 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/config"
 	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/gql"
+	"github.com/kushalpraja/library-api/handlers"
+	"github.com/kushalpraja/library-api/jobs"
+	"github.com/kushalpraja/library-api/logging"
+	"github.com/kushalpraja/library-api/metadata"
+	"github.com/kushalpraja/library-api/middleware"
+	"github.com/kushalpraja/library-api/openapi"
 	"github.com/kushalpraja/library-api/routes"
+	"github.com/kushalpraja/library-api/rpc"
+	"github.com/kushalpraja/library-api/shutdown"
+	"github.com/kushalpraja/library-api/validation"
+	"github.com/kushalpraja/library-api/webhooks"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
-	db.Connect()
-	r := gin.Default()
+	cfg := config.Load()
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	db.QueryTimeout = cfg.QueryTimeout
+	if err := db.Connect(); err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	shutdown.Register("database", func(context.Context) error {
+		return db.DB.Close()
+	})
+	middleware.IdempotencyTTL = cfg.IdempotencyTTL
+	middleware.IdempotencyLease = cfg.IdempotencyLease
+	shutdown.Register("idempotency cleanup", jobs.Every("idempotency cleanup", time.Hour, func(ctx context.Context) error {
+		n, err := db.PurgeIdempotencyKeys(ctx, time.Now().Add(-cfg.IdempotencyTTL))
+		if n > 0 {
+			slog.Info("Purged expired idempotency keys", "count", n)
+		}
+		return err
+	}))
+	shutdown.Register("event purge", jobs.Every("event purge", time.Hour, func(ctx context.Context) error {
+		n, err := db.PurgeEvents(ctx, time.Now().Add(-cfg.EventsRetention))
+		if n > 0 {
+			slog.Info("Purged old change events", "count", n)
+		}
+		return err
+	}))
+	webhooks.Client.Timeout = cfg.Webhooks.Timeout
+	webhooks.MaxAttempts = cfg.Webhooks.MaxAttempts
+	webhooks.BaseBackoff = cfg.Webhooks.Backoff
+	webhooks.MaxBackoff = cfg.Webhooks.MaxBackoff
+	webhooks.AllowPrivate = cfg.Webhooks.AllowPrivate
+	shutdown.Register("webhook delivery", jobs.Every("webhook delivery", cfg.Webhooks.Interval, webhooks.Deliver))
+	metadata.Default = metadata.NewCache(
+		metadata.NewOpenLibrary(cfg.MetadataBaseURL, cfg.MetadataTimeout),
+		cfg.MetadataCacheTTL,
+		cfg.MetadataCacheSize,
+	)
+	handlers.PublicBaseURL = cfg.PublicBaseURL
+	handlers.ReadinessTimeout = cfg.ReadinessTimeout
+
+	// route gin's own debug output through the structured logger
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("route registered", "method", method, "path", path, "handler", handler)
+	}
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		slog.Debug("gin", "message", strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+
+	if err := validation.Register(); err != nil {
+		slog.Error("Failed to register validators", "error", err)
+		os.Exit(1)
+	}
+
+	rateLimits, err := config.ParseRateLimits(cfg.RateLimits)
+	if err != nil {
+		slog.Error("Invalid RATE_LIMITS", "error", err)
+		os.Exit(1)
+	}
+
+	r := gin.New()
+	// rate limits and logs key on the client IP, which clients could pick
+	// with X-Forwarded-For if every peer were trusted as gin does by default
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		slog.Error("Invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		slog.Error("Invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+	r.Use(
+		middleware.RequestID(),
+		middleware.Logger(),
+		middleware.Metrics(),
+		middleware.Recovery(),
+		middleware.SecurityHeaders(),
+		middleware.CORS(cfg.CORS),
+		middleware.RateLimit(rateLimits, cfg.RateLimitKeysPerIP),
+		middleware.BodyLimit(int64(cfg.MaxBodyBytes)),
+		middleware.Idempotency(),
+	)
+	routes.LegacyDeprecated = cfg.LegacyDeprecated
+	routes.LegacySunset = cfg.LegacySunset
+	if err := routes.CheckLegacySunset(); err != nil {
+		slog.Error("Invalid LEGACY_ROUTES_SUNSET", "error", err)
+		os.Exit(1)
+	}
+	gql.MaxDepth = cfg.GraphQLMaxDepth
+	gql.MaxComplexity = cfg.GraphQLMaxComplexity
 	routes.SetupRoutes(r)
-	r.Run(":8080")
+	// openapi_test.go fails the build when the routes and the published
+	// spec drift apart, here it's only reported
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		slog.Warn("OpenAPI spec is out of date", "error", err)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           r,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	stop, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("Server listening", "addr", cfg.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			slog.Error("Failed to listen for gRPC", "addr", cfg.GRPCAddr, "error", err)
+			shutdown.Run(context.Background())
+			os.Exit(1)
+		}
+		grpcServer := rpc.NewServer()
+		shutdown.Register("grpc server", rpc.Stop(grpcServer))
+		go func() {
+			slog.Info("gRPC server listening", "addr", cfg.GRPCAddr)
+			serveErr <- grpcServer.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		shutdown.Run(context.Background())
+		os.Exit(1)
+	case <-stop.Done():
+	}
+	cancel() // a second signal kills the process straight away
+
+	slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout.String())
+	handlers.SetShuttingDown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, done := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	exitCode := 0
+	// stop accepting connections and wait for in-flight requests
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Requests did not drain before the deadline", "error", err)
+		exitCode = 1
+	}
+	if err := shutdown.Run(ctx); err != nil {
+		exitCode = 1
+	}
+	done()
+	slog.Info("Server stopped")
+	os.Exit(exitCode)
 }
@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	isbn    string
+	meta    *Metadata
+	err     error
+	expires time.Time
+}
+
+// Cache wraps a provider and remembers its answers, including misses,
+// so repeated lookups for the same ISBN don't hit the upstream service.
+// It holds at most size entries, dropping the least recently used first.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// most recently used first
+	order *list.List
+}
+
+// NewCache creates a cache in front of provider that keeps up to size
+// results for ttl each
+func NewCache(provider Provider, ttl time.Duration, size int) *Cache {
+	return &Cache{
+		provider: provider,
+		ttl:      ttl,
+		size:     max(size, 1),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *Cache) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if el, ok := c.entries[isbn]; ok {
+		entry := el.Value.(*cacheEntry)
+		if now.Before(entry.expires) {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			return entry.meta, entry.err
+		}
+		c.remove(el)
+	}
+	c.mu.Unlock()
+
+	meta, err := c.provider.Lookup(ctx, isbn)
+	// only cache definite answers; timeouts and upstream failures should be retried
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a concurrent lookup may have stored the same isbn meanwhile
+	if el, ok := c.entries[isbn]; ok {
+		c.remove(el)
+	}
+	c.entries[isbn] = c.order.PushFront(&cacheEntry{isbn: isbn, meta: meta, err: err, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return meta, err
+}
+
+// remove drops el; c.mu must be held
+func (c *Cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).isbn)
+}
@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingProvider answers every lookup and counts them; isbns in missing
+// are reported as not found
+type countingProvider struct {
+	calls   map[string]int
+	missing map[string]bool
+}
+
+func (p *countingProvider) Lookup(_ context.Context, isbn string) (*Metadata, error) {
+	p.calls[isbn]++
+	if p.missing[isbn] {
+		return nil, ErrNotFound
+	}
+	return &Metadata{ISBN: isbn}, nil
+}
+
+func newCountingProvider(missing ...string) *countingProvider {
+	p := &countingProvider{calls: make(map[string]int), missing: make(map[string]bool)}
+	for _, isbn := range missing {
+		p.missing[isbn] = true
+	}
+	return p
+}
+
+func TestCacheRemembersAnswers(t *testing.T) {
+	p := newCountingProvider("0000000000")
+	c := NewCache(p, time.Hour, 10)
+	ctx := context.Background()
+
+	for range 3 {
+		if meta, err := c.Lookup(ctx, "9780441172719"); err != nil || meta.ISBN != "9780441172719" {
+			t.Fatalf("Lookup = %v, %v", meta, err)
+		}
+		if _, err := c.Lookup(ctx, "0000000000"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Lookup of a missing isbn = %v, want ErrNotFound", err)
+		}
+	}
+	if p.calls["9780441172719"] != 1 || p.calls["0000000000"] != 1 {
+		t.Errorf("provider calls %v, want one per isbn", p.calls)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	p := newCountingProvider()
+	c := NewCache(p, time.Hour, 2)
+	ctx := context.Background()
+
+	c.Lookup(ctx, "a")
+	c.Lookup(ctx, "b")
+	// a is now more recently used than b, so c pushes b out
+	c.Lookup(ctx, "a")
+	c.Lookup(ctx, "c")
+	if got := c.order.Len(); got != 2 {
+		t.Fatalf("cache holds %d entries, want 2", got)
+	}
+
+	c.Lookup(ctx, "a")
+	c.Lookup(ctx, "b")
+	if p.calls["a"] != 1 || p.calls["b"] != 2 || p.calls["c"] != 1 {
+		t.Errorf("provider calls %v, want a:1 b:2 c:1", p.calls)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	p := newCountingProvider()
+	c := NewCache(p, -time.Second, 10)
+	ctx := context.Background()
+
+	c.Lookup(ctx, "a")
+	c.Lookup(ctx, "a")
+	if p.calls["a"] != 2 {
+		t.Errorf("provider called %d times for an expired entry, want 2", p.calls["a"])
+	}
+	if got := c.order.Len(); got != 1 {
+		t.Errorf("cache holds %d entries after replacing an expired one, want 1", got)
+	}
+}
@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenLibrary looks up metadata using the Open Library books API.
+// BaseURL can point at a local stub server that serves the same JSON.
+type OpenLibrary struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewOpenLibrary creates an Open Library provider with the given request timeout
+func NewOpenLibrary(baseURL string, timeout time.Duration) *OpenLibrary {
+	return &OpenLibrary{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+type openLibraryBook struct {
+	Title       string            `json:"title"`
+	Subtitle    string            `json:"subtitle"`
+	Authors     []openLibraryName `json:"authors"`
+	Publishers  []openLibraryName `json:"publishers"`
+	PublishDate string            `json:"publish_date"`
+}
+
+func (o *OpenLibrary) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	bibkey := "ISBN:" + isbn
+	query := url.Values{}
+	query.Set("bibkeys", bibkey)
+	query.Set("format", "json")
+	query.Set("jscmd", "data")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.BaseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open library returned status %d", resp.StatusCode)
+	}
+
+	// the response is keyed by bibkey and is an empty object when nothing matched
+	var body map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding open library response: %w", err)
+	}
+	book, ok := body[bibkey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	meta := &Metadata{
+		ISBN:        isbn,
+		Title:       book.Title,
+		PublishDate: book.PublishDate,
+	}
+	if book.Subtitle != "" {
+		meta.Title += ": " + book.Subtitle
+	}
+	for _, author := range book.Authors {
+		meta.Authors = append(meta.Authors, author.Name)
+	}
+	for _, publisher := range book.Publishers {
+		meta.Publishers = append(meta.Publishers, publisher.Name)
+	}
+	return meta, nil
+}
@@ -0,0 +1,26 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when a provider has no record for an ISBN
+var ErrNotFound = errors.New("no metadata found for isbn")
+
+// Metadata holds the book details a provider knows about an ISBN
+type Metadata struct {
+	ISBN        string   `json:"isbn"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Publishers  []string `json:"publishers,omitempty"`
+	PublishDate string   `json:"publish_date,omitempty"`
+}
+
+// Provider looks up book metadata by ISBN
+type Provider interface {
+	Lookup(ctx context.Context, isbn string) (*Metadata, error)
+}
+
+// Default is the provider used by the lookup handler, set up in main
+var Default Provider
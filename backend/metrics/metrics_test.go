@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("test_requests_total", "Requests handled.\nBy route, with a \\ in the help.", "method", "route")
+	latency := r.NewHistogram("test_latency_seconds", "Request latency.", []float64{1, 0.25, 0.5}, "route")
+	connected := true
+	r.NewGaugeFunc("test_connections", "Open connections.", func() (float64, bool) { return 3, connected })
+	r.NewCounterFunc("test_unknown_total", "Left out while unknown.", func() (float64, bool) { return 0, false })
+
+	requests.Inc("GET", "/books/list")
+	requests.Add(2, "GET", "/books/list")
+	requests.Inc("POST", `/say "hi"\now`+"\n")
+	for _, v := range []float64{0.25, 0.5, 0.5, 4} {
+		latency.Observe(v, "/books/list")
+	}
+	latency.Observe(0.125, "/books/add")
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	want := `# HELP test_requests_total Requests handled.\nBy route, with a \\ in the help.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",route="/books/list"} 3
+test_requests_total{method="POST",route="/say \"hi\"\\now\n"} 1
+# HELP test_latency_seconds Request latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/books/add",le="0.25"} 1
+test_latency_seconds_bucket{route="/books/add",le="0.5"} 1
+test_latency_seconds_bucket{route="/books/add",le="1"} 1
+test_latency_seconds_bucket{route="/books/add",le="+Inf"} 1
+test_latency_seconds_sum{route="/books/add"} 0.125
+test_latency_seconds_count{route="/books/add"} 1
+test_latency_seconds_bucket{route="/books/list",le="0.25"} 1
+test_latency_seconds_bucket{route="/books/list",le="0.5"} 3
+test_latency_seconds_bucket{route="/books/list",le="1"} 3
+test_latency_seconds_bucket{route="/books/list",le="+Inf"} 4
+test_latency_seconds_sum{route="/books/list"} 5.25
+test_latency_seconds_count{route="/books/list"} 4
+# HELP test_connections Open connections.
+# TYPE test_connections gauge
+test_connections 3
+# HELP test_unknown_total Left out while unknown.
+# TYPE test_unknown_total counter
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	connected = false
+	buf.Reset()
+	r.Write(&buf)
+	if bytes.Contains(buf.Bytes(), []byte("test_connections 3")) {
+		t.Errorf("gauge sample written while its value is unknown")
+	}
+}
+
+func TestDuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "A counter.")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering test_total twice didn't panic")
+		}
+	}()
+	r.NewGaugeFunc("test_total", "A gauge.", func() (float64, bool) { return 0, true })
+}
@@ -0,0 +1,7 @@
+package metrics
+
+import "runtime"
+
+var _ = NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() (float64, bool) {
+	return float64(runtime.NumGoroutine()), true
+})
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/config"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORS adds the Access-Control-* headers for requests from allowed
+// origins. Preflight requests get the allowed headers and max age here and
+// are answered by the OPTIONS handler of the route, which knows its methods.
+func CORS(cfg config.CORS) gin.HandlerFunc {
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		if !originAllowed(cfg.AllowedOrigins, origin) {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		if slices.Contains(cfg.AllowedOrigins, "*") && !cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			// credentials can't be combined with a wildcard origin
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+			h.Set("Access-Control-Max-Age", maxAge)
+			c.Set(corsMethodsKey, cfg.AllowedMethods)
+		} else if exposeHeaders != "" {
+			h.Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+const corsMethodsKey = "cors.allowed_methods"
+
+// Options answers OPTIONS for a route served with methods, listing them in
+// Allow and, for a CORS preflight from an allowed origin, in
+// Access-Control-Allow-Methods (limited to the configured methods)
+func Options(methods []string) gin.HandlerFunc {
+	allow := strings.Join(append(slices.Clone(methods), http.MethodOptions), ", ")
+	return func(c *gin.Context) {
+		c.Header("Allow", allow)
+		if allowed, ok := c.Get(corsMethodsKey); ok {
+			var permitted []string
+			for _, m := range methods {
+				if slices.Contains(allowed.([]string), m) {
+					permitted = append(permitted, m)
+				}
+			}
+			if len(permitted) > 0 {
+				c.Header("Access-Control-Allow-Methods", strings.Join(permitted, ", "))
+			}
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		switch {
+		case pattern == "*" || strings.EqualFold(pattern, origin):
+			return true
+		case strings.Contains(pattern, "://*."):
+			// https://*.example.com matches any subdomain over the same scheme
+			scheme, domain, _ := strings.Cut(pattern, "://*.")
+			originScheme, host, ok := strings.Cut(origin, "://")
+			if ok && strings.EqualFold(scheme, originScheme) && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
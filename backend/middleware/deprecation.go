@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecated marks the responses of routes that are going away. Deprecation
+// (RFC 9745) says since when, Sunset (RFC 8594) when they stop being served
+// and Link the replacement, the same path under successor.
+func Deprecated(since, sunset time.Time, successor string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		h.Set("Sunset", sunsetDate)
+		target := successor + c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		h.Add("Link", "<"+target+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
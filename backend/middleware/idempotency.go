@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses served from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyTTL is how long a stored response can be replayed
+var IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyLease is how long a key is held for a request that hasn't
+// finished. A claim left behind by a crash can be taken over after it.
+var IdempotencyLease = time.Minute
+
+// Aliases maps routes, as method and gin path, that are aliases of a
+// versioned route to the prefix of that version, so that a key used on
+// one is known on the other
+var Aliases = map[string]string{}
+
+// Idempotency makes POST requests carrying an Idempotency-Key safe to retry:
+// the first response for a key is stored and replayed to later requests
+// with the same key and body. A key reused with a different body is
+// rejected with 422, and a retry that arrives while the first request is
+// still running gets 409, for up to IdempotencyLease. Failures worth
+// retrying (5xx, 429, 499) aren't stored, so the key can be used again.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		prefix := Aliases[c.Request.Method+" "+c.FullPath()]
+		scope := idempotencyScope(c, prefix)
+		hash := requestHash(c, prefix, body)
+
+		existing, err := db.ClaimIdempotencyKey(ctx, scope, key, hash, IdempotencyTTL, IdempotencyLease)
+		if err != nil {
+			slog.ErrorContext(ctx, "idempotency key lookup failed", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+			return
+		}
+		switch {
+		case existing == nil:
+			// first request with this key, run it below
+		case existing.RequestHash != hash:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Idempotency-Key was already used for a different request",
+				"code":  "idempotency_key_reused",
+			})
+			return
+		case existing.Status == 0:
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "A request with this Idempotency-Key is still in progress",
+				"code":  "idempotency_in_progress",
+			})
+			return
+		default:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(existing.Status, existing.ContentType, existing.Body)
+			c.Abort()
+			return
+		}
+
+		w := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		// outlive a client that hung up, and release the key if the handler panics
+		saveCtx := context.WithoutCancel(ctx)
+		saved := false
+		defer func() {
+			if !saved {
+				if err := db.ReleaseIdempotencyKey(saveCtx, scope, key); err != nil {
+					slog.ErrorContext(saveCtx, "failed to release idempotency key", "error", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := w.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || status == 499 {
+			return
+		}
+		if err := db.SaveIdempotentResponse(saveCtx, scope, key, status, w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+			slog.ErrorContext(saveCtx, "failed to store idempotent response", "error", err)
+			return
+		}
+		saved = true
+	}
+}
+
+// idempotencyScope keeps keys of different routes and API keys apart.
+// prefix turns an alias into the route it stands for.
+func idempotencyScope(c *gin.Context, prefix string) string {
+	scope := c.Request.Method + " " + prefix + c.FullPath()
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		scope += " " + hex.EncodeToString(sum[:8])
+	}
+	return scope
+}
+
+func requestHash(c *gin.Context, prefix string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method+" "+prefix+c.Request.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureWriter keeps a copy of the response body
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
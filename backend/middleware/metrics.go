@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/metrics"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequests = metrics.NewCounter("library_http_requests_total",
+		"HTTP requests handled, by method, route and status.",
+		"method", "route", "status")
+	httpDuration = metrics.NewHistogram("library_http_request_duration_seconds",
+		"Time taken to handle HTTP requests, by method, route and status.",
+		metrics.DefBuckets, "method", "route", "status")
+)
+
+// Metrics records the count and latency of every request. Requests that
+// match no route are grouped under "unmatched" to keep the series bounded.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.Inc(c.Request.Method, route, status)
+		httpDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+	}
+}
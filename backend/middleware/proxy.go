@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies are the peers whose X-Forwarded-Proto is believed
+var trustedProxies []netip.Prefix
+
+// SetTrustedProxies sets the addresses or CIDR ranges of the reverse proxies
+// whose X-Forwarded-Proto header is believed, the same TRUSTED_PROXIES gin
+// follows X-Forwarded-For from
+func SetTrustedProxies(proxies []string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return fmt.Errorf("trusted proxy %q: %w", p, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q: %w", p, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	trustedProxies = prefixes
+	return nil
+}
+
+// Scheme is the scheme the client used, "http" or "https". X-Forwarded-Proto
+// only counts when the request came through a trusted proxy, any client
+// could send it.
+func Scheme(c *gin.Context) string {
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "https" && fromTrustedProxy(c) {
+		return "https"
+	}
+	return "http"
+}
+
+func fromTrustedProxy(c *gin.Context) bool {
+	addr, err := netip.ParseAddr(c.RemoteIP())
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScheme(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { trustedProxies = nil })
+
+	for _, tc := range []struct {
+		remote, proto, want string
+	}{
+		{"10.1.2.3:5000", "https", "https"},
+		{"192.0.2.1:5000", "https", "https"},
+		{"10.1.2.3:5000", "", "http"},
+		{"203.0.113.7:5000", "https", "http"},
+		{"192.0.2.2:5000", "https", "http"},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/books/1/qrcode", nil)
+		c.Request.RemoteAddr = tc.remote
+		if tc.proto != "" {
+			c.Request.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		if got := Scheme(c); got != tc.want {
+			t.Errorf("from %s with X-Forwarded-Proto %q: %s, want %s", tc.remote, tc.proto, got, tc.want)
+		}
+	}
+}
+
+func TestSetTrustedProxiesRejectsGarbage(t *testing.T) {
+	if err := SetTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("accepted a host name")
+	}
+}
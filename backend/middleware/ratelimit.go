@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/config"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idleBucketAge is how long an untouched bucket is kept; by then it has
+// refilled for any sane limit and can be recreated full
+const idleBucketAge = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter holds the buckets of every client for one route
+type limiter struct {
+	limit config.RateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// take removes a token from client's bucket. It returns whether the
+// request may go ahead, the tokens left and how long until the next token.
+func (l *limiter) take(client string, now time.Time) (ok bool, remaining float64, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleBucketAge {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > idleBucketAge {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	burst := float64(l.limit.Burst)
+	b, found := l.buckets[client]
+	if !found {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.limit.PerSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, b.tokens, time.Duration((1 - b.tokens) / l.limit.PerSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, b.tokens, 0
+}
+
+// refund returns the token take removed from client's bucket
+func (l *limiter) refund(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, found := l.buckets[client]; found {
+		b.tokens = math.Min(float64(l.limit.Burst), b.tokens+1)
+	}
+}
+
+// untilFull is how long the bucket takes to refill completely
+func (l *limiter) untilFull(remaining float64) time.Duration {
+	return time.Duration((float64(l.limit.Burst) - remaining) / l.limit.PerSecond * float64(time.Second))
+}
+
+// RateLimit throttles each client, identified by its API key or else its
+// IP, with a token bucket per route. Routes without their own entry in
+// limits share the "default" limit. Responses carry RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and requests over the
+// limit get 429 with Retry-After.
+//
+// The server doesn't check API keys, so requests with a key are also held
+// to keysPerIP times the limit per IP; otherwise every made-up key would
+// get a fresh bucket.
+func RateLimit(limits map[string]config.RateLimit, keysPerIP int) gin.HandlerFunc {
+	keysPerIP = max(keysPerIP, 1)
+	limiters := make(map[string]*limiter)
+	ceilings := make(map[string]*limiter)
+	for route, limit := range limits {
+		if !limit.Unlimited() {
+			limiters[route] = &limiter{limit: limit, buckets: make(map[string]*bucket)}
+			ceilings[route] = &limiter{
+				limit:   config.RateLimit{PerSecond: limit.PerSecond * float64(keysPerIP), Burst: limit.Burst * keysPerIP},
+				buckets: make(map[string]*bucket),
+			}
+		}
+	}
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		if _, listed := limits[route]; !listed {
+			route = "default"
+		}
+		l := limiters[route]
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		key, ip := apiKey(c), "ip:"+c.ClientIP()
+		var ok bool
+		var remaining float64
+		var wait time.Duration
+		if key == "" {
+			ok, remaining, wait = l.take(ip, now)
+		} else if ok, remaining, wait = ceilings[route].take(ip, now); !ok {
+			// report the client's own bucket, it has to wait for the ceiling
+			remaining = 0
+		} else if ok, remaining, wait = l.take(key, now); !ok {
+			ceilings[route].refund(ip)
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(l.limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(l.untilFull(remaining))))
+		c.Header("RateLimit-Policy", strconv.Itoa(l.limit.Burst)+";w="+strconv.Itoa(ceilSeconds(l.untilFull(0))))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests", "code": "rate_limited"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKey identifies a caller by its API key, hashed so keys aren't kept in
+// memory, or returns "" without one
+func apiKey(c *gin.Context) string {
+	key := c.GetHeader("X-API-Key")
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// two requests per key and, with two keys per IP, four per IP
+	r.Use(RateLimit(map[string]config.RateLimit{"default": {PerSecond: 0.001, Burst: 2}}, 2))
+	r.GET("/books", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, step := range []struct {
+		apiKey string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"", http.StatusOK},
+		{"", http.StatusTooManyRequests},
+		// a key gets its own bucket
+		{"a", http.StatusOK},
+		{"a", http.StatusOK},
+		{"a", http.StatusTooManyRequests},
+		// refused requests don't use up the IP's ceiling
+		{"b", http.StatusOK},
+		{"b", http.StatusOK},
+		// the IP has sent four requests with keys
+		{"c", http.StatusTooManyRequests},
+	} {
+		if got := send(step.apiKey); got != step.want {
+			t.Fatalf("request with key %q got %d, want %d", step.apiKey, got, step.want)
+		}
+	}
+}
@@ -1,7 +1,122 @@
 package models
 
+import (
+	"github.com/kushalpraja/library-api/metadata"
+	"time"
+)
+
+// Book is a book as the API returns it
 type Book struct {
+	ID       int64  `json:"id"`
 	BookName string `json:"book_name"`
 	Author   string `json:"author"`
 	ISBN     int    `json:"isbn"`
+	// Year of publication, zero when unknown
+	Year      int       `json:"year,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// CreateBookRequest is the body of a request adding a book
+type CreateBookRequest struct {
+	BookName string `json:"book_name" binding:"required,notblank,max=255"`
+	Author   string `json:"author" binding:"required,notblank,max=255"`
+	ISBN     int    `json:"isbn" binding:"required,isbn"`
+	Year     int    `json:"year,omitempty" binding:"omitempty,year"`
+}
+
+// UpdateBookRequest changes the fields that are set and leaves the rest
+type UpdateBookRequest struct {
+	BookName *string `json:"book_name,omitempty" binding:"omitempty,notblank,max=255"`
+	Author   *string `json:"author,omitempty" binding:"omitempty,notblank,max=255"`
+	ISBN     *int    `json:"isbn,omitempty" binding:"omitempty,isbn"`
+	Year     *int    `json:"year,omitempty" binding:"omitempty,year"`
+}
+
+// Empty reports whether the request sets no field at all
+func (r UpdateBookRequest) Empty() bool {
+	return r.BookName == nil && r.Author == nil && r.ISBN == nil && r.Year == nil
+}
+
+// EditRequest changes one field of the book with the given title
+type EditRequest struct {
+	Title string `json:"title" binding:"required,notblank,max=255"`
+	Field string `json:"field" binding:"required,oneof=Book_name Author ISBN Year"`
+	Value string `json:"value" binding:"required,notblank,max=255"`
+}
+
+// DeleteRequest removes the books with the given title
+type DeleteRequest struct {
+	Title string `json:"title" binding:"required,notblank"`
+}
+
+// LookupRequest asks the metadata provider about an ISBN
+type LookupRequest struct {
+	ISBN string `json:"isbn" binding:"required,isbn"`
+}
+
+// LabelsRequest picks the books to print on a label sheet
+type LabelsRequest struct {
+	IDs    []int64 `json:"ids" binding:"required,min=1,max=500,dive,min=1"`
+	Format string  `json:"format" binding:"omitempty,oneof=pdf svg"`
+}
+
+// LookupResponse holds the fields prefilled from an ISBN lookup
+type LookupResponse struct {
+	Book     CreateBookRequest  `json:"book"`
+	Metadata *metadata.Metadata `json:"metadata"`
+}
+
+// BatchRequest applies Operations in order in one transaction. By default
+// the first failure rolls everything back; with ContinueOnError each
+// operation that fails is undone on its own and the rest are committed.
+type BatchRequest struct {
+	Operations      []BatchOperation `json:"operations" binding:"required,min=1,max=1000"`
+	ContinueOnError bool             `json:"continue_on_error"`
+}
+
+// BatchOperation is one step of a batch. Op is "create", "update" or
+// "delete". Update and delete name the book by ID; create needs every
+// field but Year, update changes only the fields that are set. Operations
+// are validated one at a time as they run, so continue_on_error covers
+// invalid ones too.
+type BatchOperation struct {
+	Op       string  `json:"op" binding:"required,oneof=create update delete"`
+	ID       int64   `json:"id,omitempty" binding:"omitempty,min=1"`
+	BookName *string `json:"book_name,omitempty" binding:"omitempty,notblank,max=255"`
+	Author   *string `json:"author,omitempty" binding:"omitempty,notblank,max=255"`
+	ISBN     *int    `json:"isbn,omitempty" binding:"omitempty,isbn"`
+	Year     *int    `json:"year,omitempty" binding:"omitempty,year"`
+}
+
+// Update returns the book fields the operation sets
+func (op BatchOperation) Update() UpdateBookRequest {
+	return UpdateBookRequest{BookName: op.BookName, Author: op.Author, ISBN: op.ISBN, Year: op.Year}
+}
+
+// BatchResult reports the outcome of one operation with an HTTP status
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status int    `json:"status"`
+	ID     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse lists a result for every operation that was attempted.
+// Error is set when the batch was rolled back.
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ErrorResponse is the body returned with every non-2xx status
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code is set on errors a client may want to handle specially,
+	// such as "timeout" or "canceled"
+	Code string `json:"code,omitempty"`
+	// Fields maps each invalid request field to what is wrong with it
+	Fields map[string]string `json:"fields,omitempty"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Event types, one per kind of change to a book
+const (
+	BookCreated = "book.created"
+	BookUpdated = "book.updated"
+	BookDeleted = "book.deleted"
+)
+
+// Event is a change to the catalogue, as sent on the event stream and to
+// webhooks
+type Event struct {
+	// ID increases with every change, resume a stream after the last one seen
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	BookID int64  `json:"book_id"`
+	// Book after the change, or as it was before a deletion
+	Book      Book      `json:"book"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// CreateWebhookRequest is the body of a request registering a webhook
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required,http_url,max=2048"`
+	// Secret signs the deliveries; one is generated when it is left out
+	Secret string `json:"secret,omitempty" binding:"omitempty,min=16,max=255"`
+}
+
+// Webhook is a registered webhook and the state of its deliveries
+type Webhook struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Secret is only returned when the webhook is registered
+	Secret string `json:"secret,omitempty"`
+	// LastEventID is the last event delivered or given up on
+	LastEventID int64 `json:"last_event_id"`
+	// Attempts made at delivering the next event, retried from NextAttemptAt
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitzero"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
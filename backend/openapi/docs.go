@@ -0,0 +1,365 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// the subset of OpenAPI the docs page renders
+
+type document struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Tags []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"tags"`
+	// path items also hold shared parameters next to the operations
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]parameter `json:"parameters"`
+		Schemas    map[string]*schema   `json:"schemas"`
+		Responses  map[string]response  `json:"responses"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Tags        []string    `json:"tags"`
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Enum        []any              `json:"enum"`
+	Default     any                `json:"default"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+	ReadOnly    bool               `json:"readOnly"`
+}
+
+// methods are the HTTP methods in the order the docs page lists them
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+func isMethod(key string) bool {
+	for _, m := range methods {
+		if key == m {
+			return true
+		}
+	}
+	return false
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// page is what the docs template renders
+
+type page struct {
+	Title, Version, Description string
+	Sections                    []section
+	Schemas                     []namedSchema
+}
+
+type section struct {
+	Name, Description string
+	Operations        []pageOperation
+}
+
+type pageOperation struct {
+	Method, Path, Summary, Description string
+	Parameters                         []parameter
+	Body                               *mediaType
+	BodyTypes                          []string
+	Responses                          []pageResponse
+}
+
+type pageResponse struct {
+	Status, Description string
+	Schema              *schema
+	ContentTypes        []string
+}
+
+type namedSchema struct {
+	Name   string
+	Schema *schema
+}
+
+// docsPage is rendered once from the embedded spec
+var docsPage []byte
+
+func init() {
+	var buf bytes.Buffer
+	if err := docsTemplate.Execute(&buf, buildPage()); err != nil {
+		panic(fmt.Sprintf("openapi: rendering docs: %v", err))
+	}
+	docsPage = buf.Bytes()
+}
+
+// Docs serves the API reference rendered from the spec. The page is
+// self-contained, so the only thing the CSP has to allow is its inline style.
+func Docs(c *gin.Context) {
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+func buildPage() page {
+	p := page{Title: doc.Info.Title, Version: doc.Info.Version, Description: doc.Info.Description}
+
+	sectionIndex := make(map[string]int)
+	for _, t := range doc.Tags {
+		sectionIndex[t.Name] = len(p.Sections)
+		p.Sections = append(p.Sections, section{Name: t.Name, Description: t.Description})
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := doc.Paths[path]
+		var shared []parameter
+		if raw, ok := item["parameters"]; ok {
+			json.Unmarshal(raw, &shared)
+		}
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				panic(fmt.Sprintf("openapi: %s %s: %v", method, path, err))
+			}
+			po := buildOperation(strings.ToUpper(method), path, shared, op)
+
+			tag := "other"
+			if len(op.Tags) > 0 {
+				tag = op.Tags[0]
+			}
+			i, ok := sectionIndex[tag]
+			if !ok {
+				i = len(p.Sections)
+				sectionIndex[tag] = i
+				p.Sections = append(p.Sections, section{Name: tag})
+			}
+			p.Sections[i].Operations = append(p.Sections[i].Operations, po)
+		}
+	}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.Schemas = append(p.Schemas, namedSchema{Name: name, Schema: doc.Components.Schemas[name]})
+	}
+	return p
+}
+
+func buildOperation(method, path string, shared []parameter, op operation) pageOperation {
+	po := pageOperation{Method: method, Path: path, Summary: op.Summary, Description: op.Description}
+	for _, prm := range append(shared, op.Parameters...) {
+		if prm.Ref != "" {
+			prm = doc.Components.Parameters[refName(prm.Ref)]
+		}
+		po.Parameters = append(po.Parameters, prm)
+	}
+	if op.RequestBody != nil {
+		po.BodyTypes = sortedKeys(op.RequestBody.Content)
+		if len(po.BodyTypes) > 0 {
+			body := op.RequestBody.Content[po.BodyTypes[0]]
+			po.Body = &body
+		}
+	}
+
+	statuses := make([]string, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		resp := op.Responses[status]
+		if resp.Ref != "" {
+			resp = doc.Components.Responses[refName(resp.Ref)]
+		}
+		pr := pageResponse{Status: status, Description: resp.Description, ContentTypes: sortedKeys(resp.Content)}
+		if len(pr.ContentTypes) > 0 {
+			pr.Schema = resp.Content[pr.ContentTypes[0]].Schema
+		}
+		po.Responses = append(po.Responses, pr)
+	}
+	return po
+}
+
+func sortedKeys(m map[string]mediaType) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// typeOf describes a schema in a few words, linking named schemas to their
+// entry further down the page
+func typeOf(s *schema) template.HTML {
+	switch {
+	case s == nil:
+		return ""
+	case s.Ref != "":
+		name := template.HTMLEscapeString(refName(s.Ref))
+		return template.HTML(`<a href="#schema-` + name + `">` + name + `</a>`)
+	case s.Type == "array":
+		return "array of " + typeOf(s.Items)
+	}
+	t := template.HTMLEscapeString(s.Type)
+	if s.Format != "" {
+		t += " (" + template.HTMLEscapeString(s.Format) + ")"
+	}
+	return template.HTML(t)
+}
+
+// constraints lists a schema's enum and default
+func constraints(s *schema) string {
+	if s == nil {
+		return ""
+	}
+	var parts []string
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		parts = append(parts, "one of "+strings.Join(values, ", "))
+	}
+	if s.Default != nil {
+		parts = append(parts, fmt.Sprintf("default %v", s.Default))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sortedProperties(s *schema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+var docsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"typeOf":      typeOf,
+	"constraints": constraints,
+	"properties":  sortedProperties,
+	"contains":    contains,
+	"lower":       strings.ToLower,
+	"join":        strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} {{.Version}}</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+code, .path { font-family: ui-monospace, monospace; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: .2em; margin-top: 2em; }
+.op { border: 1px solid #ddd; border-radius: 6px; padding: .6em 1em; margin: 1em 0; }
+.method { display: inline-block; min-width: 4.5em; font-weight: bold; }
+.get { color: #1a7f37; } .post { color: #0550ae; } .patch { color: #9a6700; } .put { color: #9a6700; } .delete { color: #cf222e; }
+table { border-collapse: collapse; width: 100%; margin: .4em 0; }
+th, td { text-align: left; padding: .2em .6em .2em 0; vertical-align: top; border-bottom: 1px solid #eee; }
+th { font-weight: 600; font-size: 90%; color: #555; }
+.muted { color: #666; }
+.intro { white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} <span class="muted">{{.Version}}</span></h1>
+<p class="intro">{{.Description}}</p>
+<p class="muted">Machine readable: <a href="/v1/openapi.json">/v1/openapi.json</a></p>
+{{range .Sections}}
+<h2>{{.Name}}</h2>
+{{if .Description}}<p class="muted">{{.Description}}</p>{{end}}
+{{range .Operations}}
+<div class="op">
+<div><span class="method {{lower .Method}}">{{.Method}}</span> <span class="path">{{.Path}}</span></div>
+<p>{{.Summary}}{{if .Description}}<br><span class="muted">{{.Description}}</span>{{end}}</p>
+{{if .Parameters}}
+<table>
+<tr><th>Parameter</th><th>In</th><th>Type</th><th>Notes</th></tr>
+{{range .Parameters}}<tr><td><code>{{.Name}}</code>{{if .Required}} *{{end}}</td><td>{{.In}}</td><td>{{typeOf .Schema}}</td><td>{{.Description}} {{constraints .Schema}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Body}}<p>Body ({{join .BodyTypes ", "}}): {{typeOf .Body.Schema}}</p>{{end}}
+<table>
+<tr><th>Status</th><th>Description</th><th>Body</th></tr>
+{{range .Responses}}<tr><td>{{.Status}}</td><td>{{.Description}}</td><td>{{typeOf .Schema}}{{if .ContentTypes}} <span class="muted">{{join .ContentTypes ", "}}</span>{{end}}</td></tr>
+{{end}}</table>
+</div>
+{{end}}
+{{end}}
+<h2>Schemas</h2>
+{{range .Schemas}}{{$s := .Schema}}
+<h3 id="schema-{{.Name}}">{{.Name}}</h3>
+{{if $s.Description}}<p class="muted">{{$s.Description}}</p>{{end}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Notes</th></tr>
+{{range properties $s}}{{$p := index $s.Properties .}}<tr><td><code>{{.}}</code>{{if contains $s.Required .}} *{{end}}</td><td>{{typeOf $p}}{{if $p.ReadOnly}} <span class="muted">read only</span>{{end}}</td><td>{{$p.Description}} {{constraints $p}}</td></tr>
+{{end}}</table>
+{{end}}
+<p class="muted">* required</p>
+</body>
+</html>
+`))
@@ -0,0 +1,98 @@
+// Package openapi serves the API's OpenAPI 3.1 document and a reference
+// page rendered from it. The document is written by hand next to the
+// routes; CheckRoutes keeps the two from drifting apart.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// doc is the parsed spec, used by CheckRoutes and the docs page
+var doc = parse()
+
+func parse() document {
+	var d document
+	if err := json.Unmarshal(spec, &d); err != nil {
+		panic(fmt.Sprintf("openapi: parsing openapi.json: %v", err))
+	}
+	return d
+}
+
+// Handler serves the OpenAPI document
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// Prefix is the path prefix of the API version the spec describes
+const Prefix = "/v1"
+
+// CheckRoutes reports routes registered on the engine that the spec doesn't
+// describe, and operations in the spec that no route serves. OPTIONS
+// routes, generated for every path, and the deprecated unversioned aliases
+// of versioned routes are left out.
+func CheckRoutes(routes gin.RoutesInfo) error {
+	all := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		all[r.Method+" "+r.Path] = true
+	}
+
+	registered := make(map[string]bool)
+	var missing []string
+	for _, r := range routes {
+		if r.Method == http.MethodOptions || all[r.Method+" "+Prefix+r.Path] {
+			continue
+		}
+		path := specPath(r.Path)
+		registered[r.Method+" "+path] = true
+		if _, ok := doc.Paths[path][strings.ToLower(r.Method)]; !ok {
+			missing = append(missing, r.Method+" "+path)
+		}
+	}
+
+	var stale []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !isMethod(method) {
+				continue
+			}
+			op := strings.ToUpper(method) + " " + path
+			if !registered[op] {
+				stale = append(stale, op)
+			}
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems = append(problems, "not documented: "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		problems = append(problems, "documented but not routed: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("openapi.json is out of date with the routes; %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// specPath turns a gin route such as /books/:id into the OpenAPI form /books/{id}
+func specPath(route string) string {
+	parts := strings.Split(route, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
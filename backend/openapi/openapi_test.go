@@ -0,0 +1,17 @@
+package openapi_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/openapi"
+	"github.com/kushalpraja/library-api/routes"
+	"testing"
+)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.SetupRoutes(r)
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,13 +1,128 @@
+// Package routes mounts the handlers on the engine.
+//
+// The API is versioned by path prefix. Within a version, changes are
+// additive only: new routes, new optional request fields and new response
+// fields. Removing or renaming a field, changing its type or meaning, or
+// tightening what a request accepts needs a new version, served next to
+// the old one until the old one's sunset. The unversioned paths from
+// before /v1 (list, search, add, edit and delete) are aliases of /v1 that
+// answer with Deprecation, Sunset and Link headers until LegacySunset;
+// routes added since are only served under /v1.
+//
+// /graphql isn't versioned by path: its schema evolves by adding fields
+// and deprecating old ones in place.
 package routes
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/gql"
 	"github.com/kushalpraja/library-api/handlers"
+	"github.com/kushalpraja/library-api/metrics"
+	"github.com/kushalpraja/library-api/middleware"
+	"github.com/kushalpraja/library-api/openapi"
+	"time"
 )
 
+// LegacyDeprecated is when the unversioned paths were deprecated, the
+// release that introduced /v1
+var LegacyDeprecated time.Time
+
+// LegacySunset is when the unversioned paths stop being served
+var LegacySunset time.Time
+
+// MinLegacyNotice is how long clients get between the deprecation of the
+// unversioned paths and their sunset
+const MinLegacyNotice = 180 * 24 * time.Hour
+
+// CheckLegacySunset reports a LegacySunset that gives clients less than
+// MinLegacyNotice to move to /v1
+func CheckLegacySunset() error {
+	if LegacySunset.Before(LegacyDeprecated.Add(MinLegacyNotice)) {
+		return fmt.Errorf("sunset %s is less than %d days after the deprecation on %s",
+			LegacySunset.Format(time.DateOnly), int(MinLegacyNotice.Hours()/24), LegacyDeprecated.Format(time.DateOnly))
+	}
+	return nil
+}
+
 func SetupRoutes(r *gin.Engine) {
-	r.GET("/books/list", handlers.GetBooks)
-	r.POST("/books/add", handlers.AddBook)
-	r.PATCH("/books/edit", handlers.EditBook)
-	r.DELETE("/books/delete", handlers.DeleteBook)
+	registerV1(r.Group("/v1"))
+	registerLegacy(r.Group("", middleware.Deprecated(LegacyDeprecated, LegacySunset, "/v1")))
+	r.POST("/graphql", gql.Handler)
+
+	// operational endpoints aren't part of the versioned API
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+	r.GET("/version", handlers.Version)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/docs", openapi.Docs)
+	// tools that fetch the spec from its old path keep working, like /docs
+	r.GET("/openapi.json", openapi.Handler)
+
+	registerOptions(r)
+	registerAliases(r)
+}
+
+// registerV1 mounts version 1 of the API on g
+func registerV1(g *gin.RouterGroup) {
+	g.GET("/books/list", handlers.GetBooks)
+	g.GET("/books/search", handlers.SearchBooks)
+	g.GET("/books/:id", handlers.GetBook)
+	g.GET("/books/:id/barcode", handlers.GetBarcode)
+	g.GET("/books/:id/qrcode", handlers.GetQRCode)
+	g.POST("/books/add", handlers.AddBook)
+	g.PATCH("/books/edit", handlers.EditBook)
+	g.DELETE("/books/delete", handlers.DeleteBook)
+	g.PATCH("/books/:id", handlers.UpdateBook)
+	g.DELETE("/books/:id", handlers.DeleteBookByID)
+	g.POST("/books/lookup", handlers.LookupBook)
+	g.POST("/books/labels", handlers.PrintLabels)
+	g.POST("/books/batch", handlers.BatchBooks)
+	g.GET("/events", handlers.Events)
+	g.GET("/webhooks", handlers.ListWebhooks)
+	g.POST("/webhooks", handlers.CreateWebhook)
+	g.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+	g.GET("/openapi.json", openapi.Handler)
+}
+
+// registerLegacy mounts the unversioned aliases of the routes that existed
+// before /v1 on g
+func registerLegacy(g *gin.RouterGroup) {
+	g.GET("/books/list", handlers.GetBooks)
+	g.GET("/books/search", handlers.SearchBooks)
+	g.POST("/books/add", handlers.AddBook)
+	g.PATCH("/books/edit", handlers.EditBook)
+	g.DELETE("/books/delete", handlers.DeleteBook)
+}
+
+// registerAliases tells the middleware which unversioned routes are
+// aliases of /v1 routes
+func registerAliases(r *gin.Engine) {
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	aliases := make(map[string]string)
+	for _, route := range r.Routes() {
+		if registered[route.Method+" /v1"+route.Path] {
+			aliases[route.Method+" "+route.Path] = "/v1"
+		}
+	}
+	middleware.Aliases = aliases
+}
+
+// registerOptions answers OPTIONS, including CORS preflights, on every
+// path registered above
+func registerOptions(r *gin.Engine) {
+	methods := make(map[string][]string)
+	var paths []string
+	for _, route := range r.Routes() {
+		if _, seen := methods[route.Path]; !seen {
+			paths = append(paths, route.Path)
+		}
+		methods[route.Path] = append(methods[route.Path], route.Method)
+	}
+	for _, path := range paths {
+		r.OPTIONS(path, middleware.Options(methods[path]))
+	}
 }
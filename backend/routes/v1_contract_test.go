@@ -0,0 +1,415 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	"github.com/kushalpraja/library-api/metadata"
+	"github.com/kushalpraja/library-api/middleware"
+	"github.com/kushalpraja/library-api/routes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The contract of /v1: the status, and the field names and JSON types of
+// every response, errors included. A failure here means a change that
+// needs a new version, see the package comment of routes.
+
+type obj = map[string]any
+
+var (
+	message    = obj{"message": "string"}
+	errorBody  = obj{"error": "string"}
+	bookFields = obj{
+		"id":         "number",
+		"book_name":  "string",
+		"author":     "string",
+		"isbn":       "number",
+		"year":       "number",
+		"created_at": "string",
+		"updated_at": "string",
+	}
+	webhookFields = obj{
+		"id":            "number",
+		"url":           "string",
+		"last_event_id": "number",
+		"attempts":      "number",
+		"created_at":    "string",
+	}
+)
+
+func with(base obj, fields obj) obj {
+	out := obj{}
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
+
+func without(base obj, fields ...string) obj {
+	out := with(base, nil)
+	for _, f := range fields {
+		delete(out, f)
+	}
+	return out
+}
+
+func validationFailed(fields ...string) obj {
+	names := obj{}
+	for _, f := range fields {
+		names[f] = "string"
+	}
+	return obj{"error": "string", "code": "string", "fields": names}
+}
+
+type call struct {
+	method, path, body string
+	status             int
+	// shape of the JSON body, or the content type of anything else
+	shape       any
+	contentType string
+}
+
+var contract = []call{
+	{method: "POST", path: "/books/add", body: `{"book_name":"Dune","author":"Frank Herbert","isbn":9780441172719,"year":1965}`, status: 201, shape: message},
+	{method: "POST", path: "/books/add", body: `{"book_name":"Dune Messiah","author":"Frank Herbert","isbn":9780593098233}`, status: 201, shape: message},
+	{method: "POST", path: "/books/add", body: `{}`, status: 400, shape: validationFailed("book_name", "author", "isbn")},
+	{method: "POST", path: "/books/add", body: `{`, status: 400, shape: errorBody},
+	{method: "GET", path: "/books/list", status: 200, shape: []any{bookFields, without(bookFields, "year")}},
+	{method: "GET", path: "/books/search?q=messiah", status: 200, shape: []any{without(bookFields, "year")}},
+	{method: "GET", path: "/books/search?q=nothing", status: 200, shape: []any{}},
+	{method: "GET", path: "/books/search", status: 400, shape: errorBody},
+	{method: "GET", path: "/books/1", status: 200, shape: bookFields},
+	{method: "GET", path: "/books/abc", status: 400, shape: errorBody},
+	{method: "GET", path: "/books/99", status: 404, shape: errorBody},
+	{method: "PATCH", path: "/books/2", body: `{"year":1969}`, status: 200, shape: bookFields},
+	{method: "PATCH", path: "/books/2", body: `{}`, status: 400, shape: errorBody},
+	{method: "PATCH", path: "/books/2", body: `{"isbn":123}`, status: 400, shape: validationFailed("isbn")},
+	{method: "PATCH", path: "/books/99", body: `{"year":1969}`, status: 404, shape: errorBody},
+	{method: "PATCH", path: "/books/edit", body: `{"title":"Dune","field":"Author","value":"F. Herbert"}`, status: 200, shape: message},
+	{method: "PATCH", path: "/books/edit", body: `{"title":"Dune","field":"Year","value":"soon"}`, status: 400, shape: validationFailed("value")},
+	{method: "PATCH", path: "/books/edit", body: `{"title":"Emma","field":"Author","value":"Jane Austen"}`, status: 404, shape: errorBody},
+	{method: "GET", path: "/books/1/barcode", status: 200, contentType: "image/png"},
+	{method: "GET", path: "/books/1/barcode?format=svg", status: 200, contentType: "image/svg+xml"},
+	{method: "GET", path: "/books/1/barcode?format=gif", status: 400, shape: errorBody},
+	{method: "GET", path: "/books/1/qrcode", status: 200, contentType: "image/png"},
+	{method: "GET", path: "/books/99/qrcode", status: 404, shape: errorBody},
+	{method: "POST", path: "/books/labels", body: `{"ids":[1,2],"format":"svg"}`, status: 200, contentType: "image/svg+xml"},
+	{method: "POST", path: "/books/labels", body: `{"ids":[1,2]}`, status: 200, contentType: "application/pdf"},
+	{method: "POST", path: "/books/labels", body: `{"ids":[]}`, status: 400, shape: validationFailed("ids")},
+	{method: "POST", path: "/books/lookup", body: `{"isbn":"978-0-441-17271-9"}`, status: 200, shape: obj{
+		"book": obj{"book_name": "string", "author": "string", "isbn": "number", "year": "number"},
+		"metadata": obj{
+			"isbn":         "string",
+			"title":        "string",
+			"authors":      []any{"string"},
+			"publishers":   []any{"string"},
+			"publish_date": "string",
+		},
+	}},
+	{method: "POST", path: "/books/lookup", body: `{"isbn":"9780593098233"}`, status: 404, shape: errorBody},
+	{method: "POST", path: "/books/lookup", body: `{"isbn":"12"}`, status: 400, shape: validationFailed("isbn")},
+	{method: "POST", path: "/books/batch", body: `{"operations":[{"op":"create","book_name":"Emma","author":"Jane Austen","isbn":9780141439587},{"op":"update","id":3,"year":1815},{"op":"delete","id":99}],"continue_on_error":true}`, status: 200, shape: obj{
+		"committed": "boolean",
+		"results": []any{
+			obj{"index": "number", "op": "string", "status": "number", "id": "number"},
+			obj{"index": "number", "op": "string", "status": "number", "id": "number"},
+			obj{"index": "number", "op": "string", "status": "number", "id": "number", "error": "string"},
+		},
+	}},
+	{method: "POST", path: "/books/batch", body: `{"operations":[{"op":"delete","id":99}]}`, status: 404, shape: obj{
+		"committed": "boolean",
+		"results":   []any{obj{"index": "number", "op": "string", "status": "number", "id": "number", "error": "string"}},
+		"error":     "string",
+	}},
+	{method: "POST", path: "/books/batch", body: `{"operations":[]}`, status: 400, shape: validationFailed("operations")},
+	{method: "DELETE", path: "/books/delete", body: `{"title":"Emma"}`, status: 200, shape: message},
+	{method: "DELETE", path: "/books/delete", body: `{"title":"Emma"}`, status: 404, shape: errorBody},
+	{method: "DELETE", path: "/books/2", status: 200, shape: message},
+	{method: "DELETE", path: "/books/2", status: 404, shape: errorBody},
+	{method: "DELETE", path: "/books/abc", status: 400, shape: errorBody},
+	{method: "POST", path: "/webhooks", body: `{"url":"https://example.com/hook","secret":"0123456789abcdef"}`, status: 201, shape: with(webhookFields, obj{"secret": "string"})},
+	{method: "POST", path: "/webhooks", body: `{"url":"not a url"}`, status: 400, shape: validationFailed("url")},
+	{method: "POST", path: "/webhooks", body: `{"url":"http://169.254.169.254/latest"}`, status: 400, shape: validationFailed("url")},
+	{method: "GET", path: "/webhooks", status: 200, shape: []any{webhookFields}},
+	{method: "DELETE", path: "/webhooks/1", status: 200, shape: message},
+	{method: "DELETE", path: "/webhooks/1", status: 404, shape: errorBody},
+	{method: "DELETE", path: "/webhooks/abc", status: 400, shape: errorBody},
+	{method: "GET", path: "/events?after=0", status: 200, contentType: "text/event-stream"},
+	{method: "GET", path: "/events?after=first", status: 400, shape: errorBody},
+	{method: "GET", path: "/openapi.json", status: 200, contentType: "application/json; charset=utf-8"},
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	metadata.Default = fakeProvider{}
+	routes.LegacyDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	routes.LegacySunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	dbtest.Main(m)
+}
+
+func TestV1Contract(t *testing.T) {
+	for i, resp := range run(t, "/v1") {
+		call := contract[i]
+		name := call.method + " " + call.path
+		if resp.status != call.status {
+			t.Errorf("%s: status %d, want %d: %s", name, resp.status, call.status, resp.body)
+			continue
+		}
+		if call.contentType != "" {
+			if got := resp.header.Get("Content-Type"); got != call.contentType {
+				t.Errorf("%s: Content-Type %q, want %q", name, got, call.contentType)
+			}
+			continue
+		}
+		var body any
+		if err := json.Unmarshal([]byte(resp.body), &body); err != nil {
+			t.Errorf("%s: %v: %s", name, err, resp.body)
+			continue
+		}
+		if got := shape(body); !reflect.DeepEqual(got, call.shape) {
+			t.Errorf("%s: body has shape\n%v\nwant\n%v", name, got, call.shape)
+		}
+	}
+}
+
+func TestV1EventContract(t *testing.T) {
+	resp := run(t, "/v1")
+	stream := resp[len(resp)-3]
+	var data []string
+	for _, line := range strings.Split(stream.body, "\n") {
+		if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, d)
+		}
+	}
+	if len(data) == 0 {
+		t.Fatalf("no events in stream:\n%s", stream.body)
+	}
+	want := obj{
+		"id":         "number",
+		"type":       "string",
+		"book_id":    "number",
+		"book":       bookFields,
+		"created_at": "string",
+	}
+	var event any
+	if err := json.Unmarshal([]byte(data[0]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if got := shape(event); !reflect.DeepEqual(got, want) {
+		t.Errorf("event has shape\n%v\nwant\n%v", got, want)
+	}
+}
+
+// timestamps differ between the two runs
+var timestamp = regexp.MustCompile(`"\d{4}-\d\d-\d\dT[^"]*"`)
+
+func TestLegacyAliases(t *testing.T) {
+	v1 := run(t, "/v1")
+	legacy := run(t, "")
+	for i, call := range contract {
+		if !aliased(call) {
+			continue
+		}
+		name := call.method + " " + call.path
+		want, got := v1[i], legacy[i]
+		if got.status != want.status {
+			t.Errorf("%s: status %d, /v1 answered %d", name, got.status, want.status)
+		}
+		if got.header.Get("Content-Type") != want.header.Get("Content-Type") {
+			t.Errorf("%s: Content-Type %q, /v1 answered %q", name, got.header.Get("Content-Type"), want.header.Get("Content-Type"))
+		}
+		if timestamp.ReplaceAllString(got.body, `""`) != timestamp.ReplaceAllString(want.body, `""`) {
+			t.Errorf("%s: body\n%s\n/v1 answered\n%s", name, got.body, want.body)
+		}
+
+		if v := got.header.Get("Deprecation"); v != fmt.Sprintf("@%d", routes.LegacyDeprecated.Unix()) {
+			t.Errorf("%s: Deprecation %q", name, v)
+		}
+		if v := got.header.Get("Sunset"); v != "Fri, 30 Apr 2027 00:00:00 GMT" {
+			t.Errorf("%s: Sunset %q", name, v)
+		}
+		if v, link := got.header.Get("Link"), `</v1`+call.path+`>; rel="successor-version"`; v != link {
+			t.Errorf("%s: Link %q, want %q", name, v, link)
+		}
+		for _, h := range []string{"Deprecation", "Sunset", "Link"} {
+			if want.header.Get(h) != "" {
+				t.Errorf("%s: /v1 answered with %s", name, h)
+			}
+		}
+	}
+}
+
+func TestNoAliasesForNewRoutes(t *testing.T) {
+	dbtest.Open(t)
+	r := gin.New()
+	routes.SetupRoutes(r)
+	for _, call := range contract {
+		if aliased(call) || call.path == "/openapi.json" {
+			continue
+		}
+		req := httptest.NewRequest(call.method, call.path, strings.NewReader(call.body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d, want 404 for a route added with /v1", call.method, call.path, w.Code)
+		}
+	}
+}
+
+func TestUnversionedSpec(t *testing.T) {
+	r := gin.New()
+	routes.SetupRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	for _, h := range []string{"Deprecation", "Sunset", "Link"} {
+		if v := w.Header().Get(h); v != "" {
+			t.Errorf("/openapi.json answered with %s: %q", h, v)
+		}
+	}
+}
+
+func TestIdempotencyKeyAcrossAlias(t *testing.T) {
+	dbtest.Open(t)
+	r := gin.New()
+	r.Use(middleware.Idempotency())
+	routes.SetupRoutes(r)
+
+	body := `{"book_name":"Dune","author":"Frank Herbert","isbn":9780441172719}`
+	var replayed []string
+	for _, path := range []string{"/v1/books/add", "/books/add"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "add-dune")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: status %d: %s", path, w.Code, w.Body)
+		}
+		replayed = append(replayed, w.Header().Get(middleware.IdempotentReplayedHeader))
+	}
+	if replayed[0] != "" || replayed[1] != "true" {
+		t.Errorf("Idempotent-Replayed %q, want the retry through the alias replayed", replayed)
+	}
+}
+
+func TestCheckLegacySunset(t *testing.T) {
+	defer func(sunset time.Time) { routes.LegacySunset = sunset }(routes.LegacySunset)
+
+	if err := routes.CheckLegacySunset(); err != nil {
+		t.Errorf("default sunset: %v", err)
+	}
+	routes.LegacySunset = routes.LegacyDeprecated.AddDate(0, 3, 0)
+	if err := routes.CheckLegacySunset(); err == nil {
+		t.Error("accepted a sunset three months after the deprecation")
+	}
+}
+
+// legacyPaths are the paths served before /v1, the only ones with
+// unversioned aliases
+var legacyPaths = map[string]bool{
+	"/books/list":   true,
+	"/books/search": true,
+	"/books/add":    true,
+	"/books/edit":   true,
+	"/books/delete": true,
+}
+
+func aliased(c call) bool {
+	path, _, _ := strings.Cut(c.path, "?")
+	return legacyPaths[path]
+}
+
+// run makes the contract calls under prefix against a fresh database. With
+// no prefix the calls without an unversioned alias still go to /v1, so that
+// both runs see the same books.
+func run(t *testing.T, prefix string) []response {
+	t.Helper()
+	dbtest.Open(t)
+
+	r := gin.New()
+	routes.SetupRoutes(r)
+	var out []response
+	for _, call := range contract {
+		ctx := context.Background()
+		if strings.HasPrefix(call.path, "/events") {
+			// the stream only ends when the client goes away
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+		}
+		path := prefix + call.path
+		if prefix == "" && !aliased(call) {
+			path = "/v1" + call.path
+		}
+		req := httptest.NewRequestWithContext(ctx, call.method, path, strings.NewReader(call.body))
+		if call.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		out = append(out, response{status: w.Code, header: w.Header(), body: w.Body.String()})
+	}
+	return out
+}
+
+// shape replaces the values in a decoded JSON body with their JSON type,
+// null for values that are absent
+func shape(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := obj{}
+		for k, f := range v {
+			out[k] = shape(f)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = shape(e)
+		}
+		return out
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+type fakeProvider struct{}
+
+func (fakeProvider) Lookup(_ context.Context, code string) (*metadata.Metadata, error) {
+	if code != "9780441172719" {
+		return nil, metadata.ErrNotFound
+	}
+	return &metadata.Metadata{
+		ISBN:        code,
+		Title:       "Dune",
+		Authors:     []string{"Frank Herbert"},
+		Publishers:  []string{"Ace"},
+		PublishDate: "1990",
+	}, nil
+}
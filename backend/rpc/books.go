@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"context"
+	"github.com/kushalpraja/library-api/db"
+	pb "github.com/kushalpraja/library-api/librarypb"
+	"github.com/kushalpraja/library-api/models"
+	"github.com/kushalpraja/library-api/validation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+)
+
+// libraryService implements LibraryService on the data layer, with the
+// same rules as the REST handlers in handlers/book.go
+type libraryService struct {
+	pb.UnimplementedLibraryServiceServer
+}
+
+func (libraryService) ListBooks(_ *pb.ListBooksRequest, stream grpc.ServerStreamingServer[pb.Book]) error {
+	books, err := db.ListBooks(stream.Context())
+	if err != nil {
+		return serverError(stream.Context(), err)
+	}
+	for _, book := range books {
+		if err := stream.Send(toProto(book)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (libraryService) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	book, err := db.GetBook(ctx, req.GetId())
+	if err != nil {
+		return nil, notFoundOr(ctx, err)
+	}
+	return toProto(book), nil
+}
+
+func (libraryService) CreateBook(ctx context.Context, req *pb.CreateBookRequest) (*pb.Book, error) {
+	create := models.CreateBookRequest{
+		BookName: req.GetBookName(),
+		Author:   req.GetAuthor(),
+		ISBN:     int(req.GetIsbn()),
+		Year:     int(req.GetYear()),
+	}
+	if fields := validation.Struct(create); fields != nil {
+		return nil, invalidArgument(fields)
+	}
+	book, err := db.CreateBook(ctx, db.NewBook(create))
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return toProto(book), nil
+}
+
+func (libraryService) UpdateBook(ctx context.Context, req *pb.UpdateBookRequest) (*pb.Book, error) {
+	var update models.UpdateBookRequest
+	update.BookName = req.BookName
+	update.Author = req.Author
+	if req.Isbn != nil {
+		n := int(req.GetIsbn())
+		update.ISBN = &n
+	}
+	if req.Year != nil {
+		n := int(req.GetYear())
+		update.Year = &n
+	}
+	if update.Empty() {
+		return nil, status.Error(codes.InvalidArgument, "At least one of book_name, author, isbn or year is required")
+	}
+	if fields := validation.Struct(update); fields != nil {
+		return nil, invalidArgument(fields)
+	}
+
+	book, err := db.PatchBook(ctx, req.GetId(), update)
+	if err != nil {
+		return nil, notFoundOr(ctx, err)
+	}
+	return toProto(book), nil
+}
+
+func (libraryService) DeleteBook(ctx context.Context, req *pb.DeleteBookRequest) (*emptypb.Empty, error) {
+	if err := db.DeleteBook(ctx, req.GetId()); err != nil {
+		return nil, notFoundOr(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (libraryService) SearchBooks(ctx context.Context, req *pb.SearchBooksRequest) (*pb.SearchBooksResponse, error) {
+	q := strings.TrimSpace(req.GetQuery())
+	if q == "" {
+		return nil, invalidArgument(map[string]string{"query": "is required"})
+	}
+	books, err := db.SearchBooks(ctx, q)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	resp := &pb.SearchBooksResponse{Books: make([]*pb.Book, len(books))}
+	for i, book := range books {
+		resp.Books[i] = toProto(book)
+	}
+	return resp, nil
+}
+
+func toProto(b db.Book) *pb.Book {
+	book := &pb.Book{
+		Id:       b.ID,
+		BookName: b.BookName,
+		Author:   b.Author,
+		Isbn:     int64(b.ISBN),
+		Year:     int32(b.Year),
+	}
+	if !b.CreatedAt.IsZero() {
+		book.CreatedAt = timestamppb.New(b.CreatedAt)
+	}
+	if !b.UpdatedAt.IsZero() {
+		book.UpdatedAt = timestamppb.New(b.UpdatedAt)
+	}
+	return book
+}
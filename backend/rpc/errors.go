@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"sort"
+)
+
+// invalidArgument reports the invalid fields as InvalidArgument, with a
+// BadRequest detail naming each field
+func invalidArgument(fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	detail := &errdetails.BadRequest{}
+	for _, name := range names {
+		detail.FieldViolations = append(detail.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       name,
+			Description: fields[name],
+		})
+	}
+	st := status.New(codes.InvalidArgument, "Invalid request: "+validation.Summary(fields))
+	if withDetail, err := st.WithDetails(detail); err == nil {
+		st = withDetail
+	}
+	return st.Err()
+}
+
+func notFoundOr(ctx context.Context, err error) error {
+	if err == db.ErrNotFound {
+		return status.Error(codes.NotFound, "Book not found")
+	}
+	return serverError(ctx, err)
+}
+
+// serverError maps a failure the client can't fix to a status, keeping
+// cancellation and timeouts apart from real errors like the REST handlers.
+// The cause of an Internal error is only logged, it can name tables,
+// queries or files.
+func serverError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "Request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.WarnContext(ctx, "rpc timed out", "error", err)
+		return status.Error(codes.DeadlineExceeded, "Database query timed out")
+	default:
+		slog.ErrorContext(ctx, "rpc failed", "error", err)
+		return status.Error(codes.Internal, "Internal error")
+	}
+}
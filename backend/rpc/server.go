@@ -0,0 +1,120 @@
+// Package rpc serves LibraryService, the gRPC front end used by internal
+// services. It shares the data layer and validation rules with the REST
+// handlers and runs on its own listener, see config.GRPCAddr.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/kushalpraja/library-api/librarypb"
+	"github.com/kushalpraja/library-api/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// NewServer returns a server with LibraryService and reflection, so tools
+// like grpcurl can list and call the methods without the proto file
+func NewServer() *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnary),
+		grpc.ChainStreamInterceptor(logStream),
+	)
+	librarypb.RegisterLibraryServiceServer(s, libraryService{})
+	reflection.Register(s)
+	return s
+}
+
+// Stop returns a shutdown hook that lets in-flight calls finish, cutting
+// them off when ctx expires
+func Stop(s *grpc.Server) func(context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx = withRequestID(ctx)
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+		logCall(ctx, info.FullMethod, start, err)
+	}()
+	return handler(ctx, req)
+}
+
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := withRequestID(ss.Context())
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+		logCall(ctx, info.FullMethod, start, err)
+	}()
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// logCall logs one line per call like middleware.Logger, at error level
+// for server faults and warn for the caller's
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+}
+
+func recovered(ctx context.Context, method string, r any) error {
+	slog.ErrorContext(ctx, "panic while handling rpc",
+		"panic", fmt.Sprint(r),
+		"method", method,
+		"stack", string(debug.Stack()),
+	)
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+// withRequestID carries the caller's x-request-id metadata into the logs,
+// as the REST API does with the X-Request-ID header
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get("x-request-id"); len(ids) > 0 && len(ids[0]) <= 128 {
+		return logging.WithRequestID(ctx, ids[0])
+	}
+	return ctx
+}
+
+// contextStream replaces the context of a stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	pb "github.com/kushalpraja/library-api/librarypb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dbtest.Main(m)
+}
+
+// dial serves NewServer on an in-memory listener backed by a fresh
+// database and returns a connection to it
+func dial(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	dbtest.Open(t)
+
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer()
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func create(t *testing.T, client pb.LibraryServiceClient, name, author string) *pb.Book {
+	t.Helper()
+	book, err := client.CreateBook(context.Background(), &pb.CreateBookRequest{
+		BookName: name,
+		Author:   author,
+		Isbn:     9780441172719,
+		Year:     1965,
+	})
+	if err != nil {
+		t.Fatalf("CreateBook(%q): %v", name, err)
+	}
+	return book
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) *status.Status {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != code {
+		t.Fatalf("got error %v, want code %s", err, code)
+	}
+	return st
+}
+
+func TestListBooksStreams(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	names := []string{"Dune", "Emma", "Ulysses"}
+	for _, name := range names {
+		create(t, client, name, "Someone")
+	}
+
+	stream, err := client.ListBooks(context.Background(), &pb.ListBooksRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		book, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, book.GetBookName())
+	}
+	if len(got) != len(names) {
+		t.Fatalf("streamed %v, want %v", got, names)
+	}
+	for i := range names {
+		if got[i] != names[i] {
+			t.Fatalf("streamed %v, want %v", got, names)
+		}
+	}
+}
+
+func TestGetBookNotFound(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	_, err := client.GetBook(context.Background(), &pb.GetBookRequest{Id: 42})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestCreateBookInvalid(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	_, err := client.CreateBook(context.Background(), &pb.CreateBookRequest{Isbn: 9780441172719})
+	st := wantCode(t, err, codes.InvalidArgument)
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				if v.GetDescription() == "" {
+					t.Errorf("violation for %s has no description", v.GetField())
+				}
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	if len(fields) != 2 || fields[0] != "author" || fields[1] != "book_name" {
+		t.Fatalf("field violations %v, want [author book_name]", fields)
+	}
+}
+
+func TestUpdateBook(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	book := create(t, client, "Dune", "Frank Herbert")
+
+	_, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: book.GetId()})
+	wantCode(t, err, codes.InvalidArgument)
+
+	year := int32(1966)
+	updated, err := client.UpdateBook(context.Background(), &pb.UpdateBookRequest{Id: book.GetId(), Year: &year})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetYear() != 1966 || updated.GetBookName() != "Dune" {
+		t.Fatalf("updated book %v, want Dune with year 1966", updated)
+	}
+}
+
+func TestDeleteAndSearchBooks(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	dune := create(t, client, "Dune", "Frank Herbert")
+	create(t, client, "Dune Messiah", "Frank Herbert")
+	create(t, client, "Emma", "Jane Austen")
+
+	if _, err := client.DeleteBook(context.Background(), &pb.DeleteBookRequest{Id: dune.GetId()}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.DeleteBook(context.Background(), &pb.DeleteBookRequest{Id: dune.GetId()})
+	wantCode(t, err, codes.NotFound)
+
+	resp, err := client.SearchBooks(context.Background(), &pb.SearchBooksRequest{Query: "herbert"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetBooks()) != 1 || resp.GetBooks()[0].GetBookName() != "Dune Messiah" {
+		t.Fatalf("search returned %v, want only Dune Messiah", resp.GetBooks())
+	}
+
+	_, err = client.SearchBooks(context.Background(), &pb.SearchBooksRequest{Query: " "})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestReflectionListServices(t *testing.T) {
+	client := rpb.NewServerReflectionClient(dial(t))
+	stream, err := client.ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	found := false
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		if svc.GetName() == "library.v1.LibraryService" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("reflection listed %v, want library.v1.LibraryService", resp.GetListServicesResponse().GetService())
+	}
+}
+
+func TestInternalErrorHidesCause(t *testing.T) {
+	client := pb.NewLibraryServiceClient(dial(t))
+	db.DB.Close()
+
+	_, err := client.GetBook(context.Background(), &pb.GetBookRequest{Id: 1})
+	if st := wantCode(t, err, codes.Internal); st.Message() != "Internal error" {
+		t.Errorf("message %q, want the cause left out", st.Message())
+	}
+}
@@ -0,0 +1,51 @@
+// Package shutdown keeps the cleanup steps that run when the server stops,
+// such as stopping background jobs and closing the database.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+type hook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+var (
+	mu    sync.Mutex
+	hooks []hook
+)
+
+// Register adds a hook run by Run. Hooks run in reverse order of
+// registration, so something registered after the database is stopped
+// before the database is closed.
+func Register(name string, fn func(context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook{name, fn})
+}
+
+// Run calls every registered hook, even if earlier ones fail or ctx
+// expires, and returns their errors joined
+func Run(ctx context.Context) error {
+	mu.Lock()
+	pending := hooks
+	hooks = nil
+	mu.Unlock()
+
+	var errs []error
+	for i := len(pending) - 1; i >= 0; i-- {
+		h := pending[i]
+		if err := h.fn(ctx); err != nil {
+			slog.Error("Shutdown hook failed", "hook", h.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		slog.Debug("Shutdown hook finished", "hook", h.name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+// Command webhook-receiver is a local endpoint for trying out webhooks. It
+// checks the signature of each delivery and prints the event, and can
+// fail the first deliveries to show the retries:
+//
+//	go run ./utils/webhook-receiver -secret <secret> -fail 2
+//
+// then register http://localhost:9000/ with that secret, on a server
+// started with WEBHOOK_ALLOW_PRIVATE=true.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kushalpraja/library-api/webhooks"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	secret := flag.String("secret", "", "webhook secret, signatures aren't checked when empty")
+	fail := flag.Int64("fail", 0, "answer 500 to this many deliveries before accepting them")
+	flag.Parse()
+
+	var received atomic.Int64
+	http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if *secret != "" {
+			if err := webhooks.Verify(*secret, r.Header.Get(webhooks.SignatureHeader), body, time.Now(), 5*time.Minute); err != nil {
+				log.Printf("rejected event %s: %v", r.Header.Get("X-Library-Event-ID"), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		if n := received.Add(1); n <= *fail {
+			log.Printf("failing event %s on purpose (%d of %d)", r.Header.Get("X-Library-Event-ID"), n, *fail)
+			http.Error(w, "failing on purpose", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("%s %s: %s", r.Header.Get("X-Library-Event-ID"), r.Header.Get("X-Library-Event"), body)
+		fmt.Fprintln(w, "ok")
+	})
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,215 @@
+// Package validation registers the custom binding rules used by the
+// request models and turns validator errors into a message per field.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/models"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinYear is the earliest publication year accepted, the first printed books
+const MinYear = 1450
+
+// rules are the custom tags usable in binding tags
+var rules = map[string]validator.Func{
+	"notblank": notBlank,
+	"isbn":     validISBN,
+	"year":     validYear,
+}
+
+// Register adds the custom rules to gin's validator and makes errors name
+// fields by their JSON names. Call it once at startup, before serving.
+func Register() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin's validator is not go-playground/validator")
+	}
+	v.RegisterTagNameFunc(jsonName)
+	for tag, fn := range rules {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("registering %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// notBlank rejects strings that are empty once surrounding spaces are trimmed
+func notBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// validISBN checks the length and check digit of an ISBN held as text, or
+// as a number the way the library table stores it
+func validISBN(fl validator.FieldLevel) bool {
+	f := fl.Field()
+	switch f.Kind() {
+	case reflect.String:
+		return isbn.Valid(f.String())
+	case reflect.Int, reflect.Int64:
+		return f.Int() > 0 && isbn.Valid(isbn.FromInt(int(f.Int())))
+	}
+	return false
+}
+
+// validYear accepts publication years from MinYear up to next year, for
+// books announced ahead of release
+func validYear(fl validator.FieldLevel) bool {
+	y := fl.Field().Int()
+	return y >= MinYear && y <= int64(maxYear())
+}
+
+func maxYear() int {
+	return time.Now().Year() + 1
+}
+
+// Struct validates v outside of binding, e.g. the operations of a batch
+// that are checked one at a time. It returns nil when v is valid.
+func Struct(v any) map[string]string {
+	return Fields(binding.Validator.ValidateStruct(v))
+}
+
+// Var validates a single value against tag, reporting problems under name.
+// It returns nil when the value is valid.
+func Var(name string, value any, tag string) map[string]string {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if errors.As(v.Var(value, tag), &verrs) {
+		return map[string]string{name: message(verrs[0])}
+	}
+	return nil
+}
+
+// EditValue checks the value of an already bound edit request against the
+// rule of the field it sets, the value being text on the wire, and returns
+// it converted for the column
+func EditValue(req models.EditRequest) (any, map[string]string) {
+	switch req.Field {
+	case "ISBN", "Year":
+		n, err := strconv.Atoi(strings.TrimSpace(req.Value))
+		if err != nil {
+			return nil, map[string]string{"value": "must be a whole number"}
+		}
+		return n, Var("value", n, strings.ToLower(req.Field))
+	}
+	return strings.TrimSpace(req.Value), nil
+}
+
+// Fields maps the fields named in a bind or validation error to a message
+// each. It returns nil when err doesn't come from a particular field, such
+// as malformed JSON.
+func Fields(err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make(map[string]string, len(verrs))
+		for _, fe := range verrs {
+			fields[fieldPath(fe)] = message(fe)
+		}
+		return fields
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: "must be " + article(typeErr.Type.Kind())}
+	}
+	return nil
+}
+
+// Summary joins field messages into one sentence, ordered by field
+func Summary(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " " + fields[name]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fieldPath drops the struct name from the namespace, so a nested field
+// reads like the JSON path, e.g. ids[2]
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	case "year":
+		return fmt.Sprintf("must be a year between %d and %d", MinYear, maxYear())
+	case "http_url":
+		return "must be an http or https URL"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "max", "len":
+		return sizeMessage(fe)
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+func sizeMessage(fe validator.FieldError) string {
+	bound := map[string]string{"min": "at least", "max": "at most", "len": "exactly"}[fe.Tag()]
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if fe.Param() == "1" {
+			return fmt.Sprintf("must have %s 1 item", bound)
+		}
+		return fmt.Sprintf("must have %s %s items", bound, fe.Param())
+	}
+	return fmt.Sprintf("must be %s %s", bound, fe.Param())
+}
+
+func article(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a " + k.String()
+}
@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for webhook URLs that point into the
+// server's own network: loopback, private and link-local addresses. Left
+// open, a webhook could be used to make the server POST to internal
+// services or to the cloud metadata endpoint.
+var ErrPrivateAddress = errors.New("webhook address is private, loopback or link-local")
+
+// AllowPrivate lets webhooks reach private addresses, for receivers on
+// the same network as the server
+var AllowPrivate = false
+
+// CheckURL rejects a webhook URL whose host is, or resolves to, a private
+// address. A host that doesn't resolve yet is let through; the address is
+// checked again on every delivery, when it is dialed.
+func CheckURL(ctx context.Context, rawURL string) error {
+	if AllowPrivate {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return checkAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := checkAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// dialControl runs before each connection of Client is made, on the
+// address the host resolved to, so a DNS answer that changed since the
+// webhook was registered can't reach a private address either
+func dialControl(_, address string, _ syscall.RawConn) error {
+	if AllowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return checkAddr(addrPort.Addr())
+}
@@ -0,0 +1,154 @@
+// Package webhooks delivers the change events in the outbox to registered
+// webhooks. Each webhook gets the events in order, one POST per event with
+// a signed JSON body; a failed delivery is retried with exponential
+// backoff before the next event is sent.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/metrics"
+	"github.com/kushalpraja/library-api/models"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// Client sends the deliveries. Redirects aren't followed: a webhook
+	// should be registered with its final URL. It connects directly, not
+	// through a proxy from the environment, so dialControl sees the
+	// receiver's address.
+	Client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+				Control:   dialControl,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	// MaxAttempts is how often an event is tried before it is skipped
+	MaxAttempts = 10
+	// BaseBackoff is the wait after the first failure, doubled after each
+	// further one up to MaxBackoff
+	BaseBackoff = 5 * time.Second
+	MaxBackoff  = time.Hour
+)
+
+// batchSize bounds the events sent to one webhook per run
+const batchSize = 100
+
+var deliveries = metrics.NewCounter("library_webhook_deliveries_total",
+	"Webhook delivery attempts, by result: delivered, failed (will retry) or abandoned.",
+	"result")
+
+// Deliver sends the pending events of every webhook that is due, each
+// webhook in its own goroutine so a slow receiver doesn't hold up the
+// rest. Meant to run from jobs.Every.
+func Deliver(ctx context.Context) error {
+	hooks, err := db.DueWebhooks(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := deliverPending(ctx, hook); err != nil && ctx.Err() == nil {
+				slog.Error("Webhook delivery failed", "webhook", hook.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliverPending sends hook its events in order, stopping at the first
+// one that fails
+func deliverPending(ctx context.Context, hook models.Webhook) error {
+	events, err := db.EventsAfter(ctx, hook.LastEventID, batchSize)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		sendErr := send(ctx, hook, event)
+		if ctx.Err() != nil {
+			// shutting down, the attempt doesn't count
+			return nil
+		}
+		if sendErr == nil {
+			deliveries.Inc("delivered")
+			if err := db.AdvanceWebhook(ctx, hook.ID, event.ID, ""); err != nil {
+				return err
+			}
+			hook.Attempts = 0
+			continue
+		}
+
+		hook.Attempts++
+		if hook.Attempts >= MaxAttempts {
+			deliveries.Inc("abandoned")
+			slog.Warn("Webhook delivery abandoned", "webhook", hook.ID, "event", event.ID, "attempts", hook.Attempts, "error", sendErr)
+			return db.AdvanceWebhook(ctx, hook.ID, event.ID, sendErr.Error())
+		}
+		deliveries.Inc("failed")
+		wait := backoff(hook.Attempts)
+		slog.Info("Webhook delivery failed, will retry", "webhook", hook.ID, "event", event.ID, "attempts", hook.Attempts, "retry_in", wait.String(), "error", sendErr)
+		return db.RetryWebhook(ctx, hook.ID, hook.Attempts, time.Now().Add(wait), sendErr.Error())
+	}
+	return nil
+}
+
+// backoff is how long to wait after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	wait := BaseBackoff
+	for i := 1; i < attempts && wait < MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, MaxBackoff)
+}
+
+func send(ctx context.Context, hook models.Webhook, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "library-api-webhooks")
+	req.Header.Set("X-Library-Event", event.Type)
+	req.Header.Set("X-Library-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, time.Now(), body))
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/kushalpraja/library-api/db"
+	"github.com/kushalpraja/library-api/db/dbtest"
+	"github.com/kushalpraja/library-api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const secret = "0123456789abcdef0123"
+
+func TestMain(m *testing.M) {
+	dbtest.Main(m)
+}
+
+// receiver records the events it is sent, checking their signatures, and
+// fails the deliveries of events for which fail returns true
+type receiver struct {
+	t    *testing.T
+	fail func(models.Event) bool
+
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute); err != nil {
+		rc.t.Errorf("delivery with bad signature: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var event models.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		rc.t.Errorf("delivery with bad body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.events = append(rc.events, event)
+	if rc.fail != nil && rc.fail(event) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (rc *receiver) ids() []int64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	ids := make([]int64, len(rc.events))
+	for i, e := range rc.events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// setup opens a fresh database with a webhook pointing at a receiver that
+// fails the events fail returns true for
+func setup(t *testing.T, fail func(models.Event) bool) (*receiver, models.Webhook) {
+	t.Helper()
+	dbtest.Open(t)
+
+	allowPrivate, maxAttempts, baseBackoff, maxBackoff := AllowPrivate, MaxAttempts, BaseBackoff, MaxBackoff
+	t.Cleanup(func() {
+		AllowPrivate, MaxAttempts, BaseBackoff, MaxBackoff = allowPrivate, maxAttempts, baseBackoff, maxBackoff
+	})
+	// the receiver listens on loopback
+	AllowPrivate = true
+
+	rc := &receiver{t: t, fail: fail}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	hook, err := db.AddWebhook(context.Background(), srv.URL, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rc, hook
+}
+
+func webhook(t *testing.T, id int64) models.Webhook {
+	t.Helper()
+	hooks, err := db.ListWebhooks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hooks {
+		if h.ID == id {
+			return h
+		}
+	}
+	t.Fatalf("webhook %d not found", id)
+	return models.Webhook{}
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDeliverInOrder(t *testing.T) {
+	rc, hook := setup(t, nil)
+	dbtest.AddBooks(t, "Dune", "Emma", "Ulysses")
+
+	if err := Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.ids(); !equal(got, []int64{1, 2, 3}) {
+		t.Fatalf("delivered events %v, want [1 2 3]", got)
+	}
+	if got := rc.events[2].Book.BookName; got != "Ulysses" {
+		t.Errorf("event 3 is about %q, want Ulysses", got)
+	}
+
+	hook = webhook(t, hook.ID)
+	if hook.LastEventID != 3 || hook.Attempts != 0 || hook.LastError != "" {
+		t.Errorf("webhook after delivery: %+v", hook)
+	}
+
+	// nothing is due until the next change
+	if err := Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.ids(); len(got) != 3 {
+		t.Fatalf("delivered events %v after a second run, want [1 2 3]", got)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	rc, hook := setup(t, func(models.Event) bool { return true })
+	BaseBackoff, MaxBackoff = time.Minute, time.Hour
+	dbtest.AddBooks(t, "Dune", "Emma")
+
+	for n := 1; n <= 3; n++ {
+		before := time.Now()
+		if err := deliverPending(context.Background(), webhook(t, hook.ID)); err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now()
+
+		got := webhook(t, hook.ID)
+		if got.Attempts != n || got.LastEventID != 0 {
+			t.Fatalf("after failure %d: attempts %d, last event %d", n, got.Attempts, got.LastEventID)
+		}
+		if !strings.Contains(got.LastError, "500") {
+			t.Errorf("after failure %d: last error %q", n, got.LastError)
+		}
+		// next_attempt_at is stored in whole seconds
+		earliest := before.Add(backoff(n)).Truncate(time.Second)
+		latest := after.Add(backoff(n))
+		if got.NextAttemptAt.Before(earliest) || got.NextAttemptAt.After(latest) {
+			t.Errorf("after failure %d: next attempt at %v, want %v from now", n, got.NextAttemptAt, backoff(n))
+		}
+	}
+
+	// the second event waits for the first
+	if got := rc.ids(); !equal(got, []int64{1, 1, 1}) {
+		t.Fatalf("delivered events %v, want [1 1 1]", got)
+	}
+	// and the webhook isn't due while it waits to retry
+	if err := Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.ids(); len(got) != 3 {
+		t.Fatalf("delivered events %v before the retry was due", got)
+	}
+}
+
+func TestDeliverAbandonsAfterMaxAttempts(t *testing.T) {
+	rc, hook := setup(t, func(e models.Event) bool { return e.ID == 1 })
+	MaxAttempts = 3
+	dbtest.AddBooks(t, "Dune", "Emma")
+
+	for n := 1; n <= MaxAttempts; n++ {
+		if err := deliverPending(context.Background(), webhook(t, hook.ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := webhook(t, hook.ID)
+	if got.LastEventID != 1 || got.Attempts != 0 || got.LastError == "" {
+		t.Fatalf("webhook after giving up on event 1: %+v", got)
+	}
+
+	if err := Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.ids(); !equal(got, []int64{1, 1, 1, 2}) {
+		t.Fatalf("delivered events %v, want [1 1 1 2]", got)
+	}
+	got = webhook(t, hook.ID)
+	if got.LastEventID != 2 || got.LastError != "" {
+		t.Fatalf("webhook after delivering event 2: %+v", got)
+	}
+}
+
+func TestDeliverRefusesPrivateAddress(t *testing.T) {
+	rc, hook := setup(t, nil)
+	AllowPrivate = false
+	dbtest.AddBooks(t, "Dune")
+
+	if err := Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.ids(); len(got) != 0 {
+		t.Fatalf("delivered events %v to a loopback address", got)
+	}
+	got := webhook(t, hook.ID)
+	if got.Attempts != 1 || !strings.Contains(got.LastError, ErrPrivateAddress.Error()) {
+		t.Fatalf("webhook after refused delivery: %+v", got)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for url, want := range map[string]error{
+		"https://93.184.215.14/hook":        nil,
+		"http://127.0.0.1:9000/":            ErrPrivateAddress,
+		"http://localhost/":                 ErrPrivateAddress,
+		"http://10.1.2.3/":                  ErrPrivateAddress,
+		"http://192.168.0.10/":              ErrPrivateAddress,
+		"http://169.254.169.254/latest":     ErrPrivateAddress,
+		"http://[::1]/":                     ErrPrivateAddress,
+		"http://[::ffff:127.0.0.1]/":        ErrPrivateAddress,
+		"http://[fe80::1]/":                 ErrPrivateAddress,
+		"http://0.0.0.0/":                   ErrPrivateAddress,
+		"http://does-not-resolve.invalid/x": nil,
+	} {
+		if err := CheckURL(context.Background(), url); !errors.Is(err, want) {
+			t.Errorf("CheckURL(%q) = %v, want %v", url, err, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, limit := BaseBackoff, MaxBackoff
+	t.Cleanup(func() { BaseBackoff, MaxBackoff = base, limit })
+	BaseBackoff, MaxBackoff = 5*time.Second, time.Minute
+
+	for attempts, want := range map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		3:  20 * time.Second,
+		4:  40 * time.Second,
+		5:  time.Minute,
+		50: time.Minute,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kushalpraja/library-api/client"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/models"
+)
+
+// Exit codes returned by the non-interactive subcommands
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitUnavailable = 4
+	exitQueued      = 5 // writes were saved offline, not yet on the server
+	exitConflict    = 6 // replaying offline writes produced conflicts
+)
+
+const usageText = `Usage: library-api-cli [command] [flags]
+
+Run without a command to start the interactive interface.
+
+Commands:
+  list                       list all books
+  get <id>                   show a single book
+  search <query>             find books by title, author, ISBN or year
+  add                        add a book (--title, --author, --isbn, optional --year) or many from --stdin
+  edit                       change a field (--title, --field, --value) or many from --stdin
+  delete                     delete a book (--title) or many from --stdin, one title per line
+  sync                       send changes queued while the server was unreachable
+  conflicts                  show queued changes that could not be applied (--clear to forget them)
+
+Flags for every command:
+  --server URL               API address (default $LIBRARY_API_URL or http://localhost:8080)
+  --output table|json|csv    output format (default table)
+`
+
+// commandResult is the outcome of one write operation
+type commandResult struct {
+	Target  string `json:"target"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// commandEnv holds the flags shared by every subcommand
+type commandEnv struct {
+	args   []string
+	output string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// runCommand executes a subcommand and returns the process exit code
+func runCommand(args []string) int {
+	env := &commandEnv{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	return env.run(args)
+}
+
+func (env *commandEnv) run(args []string) int {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprint(env.stdout, usageText)
+		return exitOK
+	}
+
+	commands := map[string]func(*commandEnv, *flag.FlagSet, []string) int{
+		"list":      cmdList,
+		"get":       cmdGet,
+		"search":    cmdSearch,
+		"add":       cmdAdd,
+		"edit":      cmdEdit,
+		"delete":    cmdDelete,
+		"sync":      cmdSync,
+		"conflicts": cmdConflicts,
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n\n%s", name, usageText)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.StringVar(&serverURL, "server", serverURL, "API address")
+	fs.StringVar(&env.output, "output", "table", "output format: table, json or csv")
+	code := command(env, fs, args[1:])
+	if notice := pendingNotice(); notice != "" && name != "conflicts" {
+		fmt.Fprintln(env.stderr, "note: "+notice)
+	}
+	return code
+}
+
+// parseFlags parses the subcommand flags, which may appear before or after
+// positional arguments, and validates the shared ones
+func (env *commandEnv) parseFlags(fs *flag.FlagSet, args []string) bool {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return false
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	env.args = positional
+	serverURL = strings.TrimRight(serverURL, "/")
+	api = newAPIClient()
+	switch env.output {
+	case "table", "json", "csv":
+		return true
+	}
+	fmt.Fprintf(env.stderr, "invalid --output %q, expected table, json or csv\n", env.output)
+	return false
+}
+
+// fail prints err and maps it onto an exit code
+func (env *commandEnv) fail(err error) int {
+	fmt.Fprintf(env.stderr, "error: %v\n", err)
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrUnavailable):
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// commandContext bounds a single API call made by a subcommand
+func commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), requestTimeout)
+}
+
+func cmdList(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	books, offline, err := listBooks(ctx)
+	if err != nil {
+		return env.fail(err)
+	}
+	env.offlineNote(offline)
+	return env.printBooks(books)
+}
+
+func cmdGet(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	if !env.parseFlags(fs, args) {
+		return exitUsage
+	}
+	if len(env.args) != 1 {
+		fmt.Fprintln(env.stderr, "usage: get <id>")
+		return exitUsage
+	}
+	id, err := strconv.ParseInt(env.args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "invalid id %q\n", env.args[0])
+		return exitUsage
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	book, offline, err := getBook(ctx, id)
+	if err != nil {
+		return env.fail(err)
+	}
+	env.offlineNote(offline)
+	if env.output == "json" {
+		writeJSON(env.stdout, book)
+		return exitOK
+	}
+	return env.printBooks([]models.Book{book})
+}
+
+func cmdSearch(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	if !env.parseFlags(fs, args) {
+		return exitUsage
+	}
+	if len(env.args) == 0 {
+		fmt.Fprintln(env.stderr, "usage: search <query>")
+		return exitUsage
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	books, offline, err := searchBooks(ctx, strings.Join(env.args, " "))
+	if err != nil {
+		return env.fail(err)
+	}
+	env.offlineNote(offline)
+	if len(books) == 0 {
+		env.printBooks(books)
+		return exitNotFound
+	}
+	return env.printBooks(books)
+}
+
+func cmdAdd(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	var book models.Book
+	var fromStdin, csvInput bool
+	fs.StringVar(&book.BookName, "title", "", "book title")
+	fs.StringVar(&book.Author, "author", "", "book author")
+	fs.IntVar(&book.ISBN, "isbn", 0, "book ISBN")
+	fs.IntVar(&book.Year, "year", 0, "year of publication")
+	fs.BoolVar(&fromStdin, "stdin", false, "read books from stdin as JSON (array or one object per line)")
+	fs.BoolVar(&csvInput, "csv", false, "with --stdin, read CSV with a title,author,isbn[,year] header instead of JSON")
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+
+	books := []models.Book{book}
+	if fromStdin {
+		var err error
+		if csvInput {
+			books, err = readBooksCSV(env.stdin)
+		} else {
+			books, err = readBooksJSON(env.stdin)
+		}
+		if err != nil {
+			fmt.Fprintf(env.stderr, "reading stdin: %v\n", err)
+			return exitUsage
+		}
+	} else if book.BookName == "" || book.Author == "" || book.ISBN == 0 {
+		fmt.Fprintln(env.stderr, "usage: add --title <title> --author <author> --isbn <isbn> [--year <year>] or add --stdin")
+		return exitUsage
+	} else if !isbn.Valid(isbn.FromInt(book.ISBN)) {
+		fmt.Fprintf(env.stderr, "invalid --isbn %d, expected an ISBN-10 or ISBN-13 with its check digit\n", book.ISBN)
+		return exitUsage
+	}
+
+	return env.runBulk(len(books), func(i int) (string, bool, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+		queued, err := addBook(ctx, books[i])
+		return books[i].BookName, queued, err
+	})
+}
+
+func cmdEdit(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	var edit models.EditRequest
+	var fromStdin bool
+	fs.StringVar(&edit.Title, "title", "", "title of the book to edit")
+	fs.StringVar(&edit.Field, "field", "", "field to change: Book_name, Author, ISBN or Year")
+	fs.StringVar(&edit.Value, "value", "", "new value")
+	fs.BoolVar(&fromStdin, "stdin", false, `read edits from stdin as JSON lines of {"title","field","value"}`)
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+
+	edits := []models.EditRequest{edit}
+	if fromStdin {
+		edits = nil
+		dec := json.NewDecoder(env.stdin)
+		for {
+			var e models.EditRequest
+			if err := dec.Decode(&e); err == io.EOF {
+				break
+			} else if err != nil {
+				fmt.Fprintf(env.stderr, "reading stdin: %v\n", err)
+				return exitUsage
+			}
+			edits = append(edits, e)
+		}
+	} else if edit.Title == "" || edit.Field == "" || edit.Value == "" {
+		fmt.Fprintln(env.stderr, "usage: edit --title <title> --field <Book_name|Author|ISBN|Year> --value <value> or edit --stdin")
+		return exitUsage
+	}
+
+	return env.runBulk(len(edits), func(i int) (string, bool, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+		queued, err := editBook(ctx, edits[i])
+		return edits[i].Title, queued, err
+	})
+}
+
+func cmdDelete(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	var title string
+	var fromStdin bool
+	fs.StringVar(&title, "title", "", "title of the book to delete")
+	fs.BoolVar(&fromStdin, "stdin", false, "read titles from stdin, one per line")
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+
+	titles := []string{title}
+	if fromStdin {
+		titles = nil
+		scanner := bufio.NewScanner(env.stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				titles = append(titles, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(env.stderr, "reading stdin: %v\n", err)
+			return exitUsage
+		}
+	} else if title == "" {
+		fmt.Fprintln(env.stderr, "usage: delete --title <title> or delete --stdin")
+		return exitUsage
+	}
+
+	return env.runBulk(len(titles), func(i int) (string, bool, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+		queued, err := deleteBook(ctx, titles[i])
+		return titles[i], queued, err
+	})
+}
+
+// runBulk runs n write operations, reports each outcome and returns the exit
+// code of the first failure so scripts can tell that something went wrong.
+// Writes saved offline succeed with exitQueued.
+func (env *commandEnv) runBulk(n int, op func(i int) (string, bool, error)) int {
+	code := exitOK
+	results := make([]commandResult, 0, n)
+	for i := 0; i < n; i++ {
+		target, queued, err := op(i)
+		result := commandResult{Target: target, Message: "ok"}
+		if queued {
+			result.Message = "queued offline"
+			if code == exitOK {
+				code = exitQueued
+			}
+		}
+		if err != nil {
+			result = commandResult{Target: target, Error: err.Error()}
+			if code == exitOK || code == exitQueued {
+				code = exitCode(err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	switch env.output {
+	case "json":
+		writeJSON(env.stdout, results)
+	case "csv":
+		w := csv.NewWriter(env.stdout)
+		w.Write([]string{"target", "message", "error"})
+		for _, r := range results {
+			w.Write([]string{r.Target, r.Message, r.Error})
+		}
+		w.Flush()
+	default:
+		tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TARGET\tRESULT")
+		for _, r := range results {
+			outcome := r.Message
+			if r.Error != "" {
+				outcome = "error: " + r.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", r.Target, outcome)
+		}
+		tw.Flush()
+	}
+	return code
+}
+
+func cmdSync(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	report, err := syncOffline(ctx)
+	if err != nil {
+		return env.fail(err)
+	}
+	if env.output == "json" {
+		writeJSON(env.stdout, report)
+	} else {
+		fmt.Fprintf(env.stdout, "applied %d queued change(s), %d conflict(s), %d still pending\n",
+			report.Applied, len(report.Conflicts), report.Pending)
+		env.printConflicts(report.Conflicts)
+	}
+	if len(report.Conflicts) > 0 {
+		return exitConflict
+	}
+	return exitOK
+}
+
+func cmdConflicts(env *commandEnv, fs *flag.FlagSet, args []string) int {
+	var clearAll bool
+	fs.BoolVar(&clearAll, "clear", false, "forget the recorded conflicts")
+	if !env.parseFlags(fs, args) || len(env.args) != 0 {
+		return exitUsage
+	}
+	if clearAll {
+		if err := clearConflicts(); err != nil {
+			return env.fail(err)
+		}
+		return exitOK
+	}
+
+	conflicts := listConflicts()
+	if env.output == "json" {
+		if conflicts == nil {
+			conflicts = []conflict{}
+		}
+		writeJSON(env.stdout, conflicts)
+	} else {
+		env.printConflicts(conflicts)
+	}
+	if len(conflicts) > 0 {
+		return exitConflict
+	}
+	return exitOK
+}
+
+func (env *commandEnv) printConflicts(conflicts []conflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+	if env.output == "csv" {
+		w := csv.NewWriter(env.stdout)
+		w.Write([]string{"queued_at", "kind", "target", "reason"})
+		for _, c := range conflicts {
+			w.Write([]string{c.Op.QueuedAt.Format(time.RFC3339), c.Op.Kind, conflictTarget(c.Op), c.Reason})
+		}
+		w.Flush()
+		return
+	}
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUEUED\tKIND\tTARGET\tREASON")
+	for _, c := range conflicts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Op.QueuedAt.Format("Jan 2 15:04"), c.Op.Kind, conflictTarget(c.Op), c.Reason)
+	}
+	tw.Flush()
+}
+
+func conflictTarget(op queuedOp) string {
+	switch op.Kind {
+	case opAdd:
+		return op.Book.BookName
+	case opDeleteID:
+		return fmt.Sprintf("%s (#%d)", op.Book.BookName, op.Book.ID)
+	case opEdit:
+		return fmt.Sprintf("%s: %s=%s", op.Title, op.Edit.Field, op.Edit.Value)
+	case opUpdate:
+		return fmt.Sprintf("%s (#%d): %s", op.Book.BookName, op.Book.ID, describeUpdate(op.Update))
+	}
+	return op.Title
+}
+
+// describeUpdate lists the fields an update sets, like book_name=Dune, year=1965
+func describeUpdate(update models.UpdateBookRequest) string {
+	var fields []string
+	if update.BookName != nil {
+		fields = append(fields, "book_name="+*update.BookName)
+	}
+	if update.Author != nil {
+		fields = append(fields, "author="+*update.Author)
+	}
+	if update.ISBN != nil {
+		fields = append(fields, "isbn="+strconv.Itoa(*update.ISBN))
+	}
+	if update.Year != nil {
+		fields = append(fields, "year="+strconv.Itoa(*update.Year))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// offlineNote tells the user when results came from the offline cache
+func (env *commandEnv) offlineNote(offline bool) {
+	if offline {
+		fmt.Fprintf(env.stderr, "note: server unreachable, showing offline copy (%s)\n", cacheAge())
+	}
+}
+
+func (env *commandEnv) printBooks(books []models.Book) int {
+	if books == nil {
+		books = []models.Book{}
+	}
+	switch env.output {
+	case "json":
+		writeJSON(env.stdout, books)
+	case "csv":
+		w := csv.NewWriter(env.stdout)
+		w.Write([]string{"id", "title", "author", "isbn", "year"})
+		for _, b := range books {
+			w.Write([]string{strconv.FormatInt(b.ID, 10), b.BookName, b.Author, strconv.Itoa(b.ISBN), yearText(b.Year)})
+		}
+		w.Flush()
+	default:
+		tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tAUTHOR\tISBN\tYEAR")
+		for _, b := range books {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", b.ID, b.BookName, b.Author, b.ISBN, yearText(b.Year))
+		}
+		tw.Flush()
+	}
+	return exitOK
+}
+
+// yearText shows an unknown year, stored as 0, as blank
+func yearText(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// readBooksJSON accepts either a JSON array of books or one book object per line
+func readBooksJSON(r io.Reader) ([]models.Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var books []models.Book
+		err := json.Unmarshal(data, &books)
+		return books, err
+	}
+
+	var books []models.Book
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var book models.Book
+		if err := dec.Decode(&book); err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// readBooksCSV reads rows of title,author,isbn and an optional year after a
+// header line
+func readBooksCSV(r io.Reader) ([]models.Book, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var books []models.Book
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected title,author,isbn", i+1)
+		}
+		isbn, err := strconv.Atoi(strings.TrimSpace(record[2]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid isbn %q", i+1, record[2])
+		}
+		book := models.Book{BookName: record[0], Author: record[1], ISBN: isbn}
+		if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
+			if book.Year, err = strconv.Atoi(strings.TrimSpace(record[3])); err != nil {
+				return nil, fmt.Errorf("line %d: invalid year %q", i+1, record[3])
+			}
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runCLI runs a subcommand against srv with stdin as its input
+func runCLI(t *testing.T, srv *stubLibrary, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	prev := serverURL
+	serverURL = srv.URL
+	t.Cleanup(func() { serverURL = prev })
+
+	var out, errOut bytes.Buffer
+	env := &commandEnv{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}
+	code = env.run(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	dated := dune
+	dated.Year = 1965
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		offline bool
+		want    int
+		// text expected in stdout and stderr, and text stderr must not have
+		stdout, stderr, notStderr string
+	}{
+		{name: "help", args: []string{"help"}, want: exitOK, stdout: "Commands:"},
+		{name: "unknown command", args: []string{"lend"}, want: exitUsage, stderr: `unknown command "lend"`},
+		{name: "list", args: []string{"list"}, want: exitOK, stdout: "1965"},
+		{name: "list as CSV", args: []string{"list", "--output", "csv"}, want: exitOK, stdout: "id,title,author,isbn,year\n1,Dune,Frank Herbert,9780441172719,1965\n"},
+		{name: "list with arguments", args: []string{"list", "everything"}, want: exitUsage},
+		{name: "invalid output", args: []string{"list", "--output", "xml"}, want: exitUsage, stderr: `invalid --output "xml"`},
+		{name: "get", args: []string{"get", "1"}, want: exitOK, stdout: "Dune"},
+		{name: "get missing book", args: []string{"get", "99"}, want: exitNotFound, stderr: "Book not found"},
+		{name: "get without id", args: []string{"get"}, want: exitUsage, stderr: "usage: get <id>"},
+		{name: "get invalid id", args: []string{"get", "first"}, want: exitUsage, stderr: `invalid id "first"`},
+		{name: "get invalid output", args: []string{"get", "1", "--output", "xml"}, want: exitUsage, stderr: `invalid --output "xml"`, notStderr: "usage: get"},
+		{name: "search", args: []string{"search", "1965"}, want: exitOK, stdout: "Dune"},
+		{name: "search without matches", args: []string{"search", "Ulysses"}, want: exitNotFound},
+		{name: "search without query", args: []string{"search"}, want: exitUsage, stderr: "usage: search <query>"},
+		{name: "add", args: []string{"add", "--title", "Emma", "--author", "Jane Austen", "--isbn", "9780141439587", "--year", "1815"}, want: exitOK},
+		{name: "add without isbn", args: []string{"add", "--title", "Emma", "--author", "Jane Austen"}, want: exitUsage, stderr: "--isbn <isbn>"},
+		{name: "add invalid isbn", args: []string{"add", "--title", "Emma", "--author", "Jane Austen", "--isbn", "9780141439588"}, want: exitUsage, stderr: "invalid --isbn 9780141439588"},
+		{name: "add while offline", args: []string{"add", "--title", "Emma", "--author", "Jane Austen", "--isbn", "9780141439587"}, offline: true, want: exitQueued, stdout: "queued offline"},
+		{name: "list while offline", args: []string{"list"}, offline: true, want: exitOK, stderr: "showing offline copy"},
+		{name: "edit", args: []string{"edit", "--title", "Dune", "--field", "Year", "--value", "1966"}, want: exitOK},
+		{name: "edit missing book", args: []string{"edit", "--title", "Emma", "--field", "Year", "--value", "1815"}, want: exitNotFound},
+		{name: "edit without value", args: []string{"edit", "--title", "Dune", "--field", "Year"}, want: exitUsage},
+		{name: "delete", args: []string{"delete", "--title", "Dune"}, want: exitOK},
+		{name: "delete missing book", args: []string{"delete", "--title", "Emma"}, want: exitNotFound},
+		{name: "delete without title", args: []string{"delete"}, want: exitUsage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newStubLibrary(t, dated)
+			useStore(t)
+			if tc.offline {
+				goOffline(t, srv)
+			}
+			code, stdout, stderr := runCLI(t, srv, "", tc.args...)
+			if code != tc.want {
+				t.Errorf("exit code %d, want %d\nstdout: %s\nstderr: %s", code, tc.want, stdout, stderr)
+			}
+			if !strings.Contains(stdout, tc.stdout) {
+				t.Errorf("stdout %q, want it to contain %q", stdout, tc.stdout)
+			}
+			if !strings.Contains(stderr, tc.stderr) {
+				t.Errorf("stderr %q, want it to contain %q", stderr, tc.stderr)
+			}
+			if tc.notStderr != "" && strings.Contains(stderr, tc.notStderr) {
+				t.Errorf("stderr %q, want it without %q", stderr, tc.notStderr)
+			}
+		})
+	}
+}
+
+func TestBulkFromStdin(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		args  []string
+		stdin string
+		want  int
+		// the catalogue afterwards, by title and year
+		titles []string
+		years  []int
+	}{
+		{
+			name:   "add JSON array",
+			args:   []string{"add", "--stdin"},
+			stdin:  `[{"book_name":"Emma","author":"Jane Austen","isbn":9780141439587,"year":1815}]`,
+			want:   exitOK,
+			titles: []string{"Dune", "Emma"},
+			years:  []int{0, 1815},
+		},
+		{
+			name: "add JSON lines",
+			args: []string{"add", "--stdin"},
+			stdin: `{"book_name":"Emma","author":"Jane Austen","isbn":9780141439587}
+{"book_name":"Persuasion","author":"Jane Austen","isbn":9780141439686}`,
+			want:   exitOK,
+			titles: []string{"Dune", "Emma", "Persuasion"},
+			years:  []int{0, 0, 0},
+		},
+		{
+			name:   "add CSV with years",
+			args:   []string{"add", "--stdin", "--csv"},
+			stdin:  "title,author,isbn,year\nEmma,Jane Austen,9780141439587,1815\nPersuasion,Jane Austen,9780141439686,\n",
+			want:   exitOK,
+			titles: []string{"Dune", "Emma", "Persuasion"},
+			years:  []int{0, 1815, 0},
+		},
+		{
+			name:   "add CSV without years",
+			args:   []string{"add", "--stdin", "--csv"},
+			stdin:  "title,author,isbn\nEmma,Jane Austen,9780141439587\n",
+			want:   exitOK,
+			titles: []string{"Dune", "Emma"},
+			years:  []int{0, 0},
+		},
+		{
+			name:   "add CSV with a bad isbn",
+			args:   []string{"add", "--stdin", "--csv"},
+			stdin:  "title,author,isbn\nEmma,Jane Austen,soon\n",
+			want:   exitUsage,
+			titles: []string{"Dune"},
+			years:  []int{0},
+		},
+		{
+			name:   "add malformed JSON",
+			args:   []string{"add", "--stdin"},
+			stdin:  `[{"book_name":`,
+			want:   exitUsage,
+			titles: []string{"Dune"},
+			years:  []int{0},
+		},
+		{
+			name:   "edit JSON lines",
+			args:   []string{"edit", "--stdin"},
+			stdin:  `{"title":"Dune","field":"Year","value":"1965"}` + "\n" + `{"title":"Dune","field":"Author","value":"F. Herbert"}`,
+			want:   exitOK,
+			titles: []string{"Dune"},
+			years:  []int{1965},
+		},
+		{
+			name:   "edit carries on after a failure",
+			args:   []string{"edit", "--stdin"},
+			stdin:  `{"title":"Emma","field":"Year","value":"1815"}` + "\n" + `{"title":"Dune","field":"Year","value":"1965"}`,
+			want:   exitNotFound,
+			titles: []string{"Dune"},
+			years:  []int{1965},
+		},
+		{
+			name:   "delete titles",
+			args:   []string{"delete", "--stdin"},
+			stdin:  "Dune\n\n",
+			want:   exitOK,
+			titles: []string{},
+			years:  []int{},
+		},
+		{
+			name:   "delete a missing title",
+			args:   []string{"delete", "--stdin"},
+			stdin:  "Emma\nDune\n",
+			want:   exitNotFound,
+			titles: []string{},
+			years:  []int{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newStubLibrary(t, dune)
+			useStore(t)
+			code, stdout, stderr := runCLI(t, srv, tc.stdin, tc.args...)
+			if code != tc.want {
+				t.Errorf("exit code %d, want %d\nstdout: %s\nstderr: %s", code, tc.want, stdout, stderr)
+			}
+			titles, years := []string{}, []int{}
+			for _, b := range srv.snapshot() {
+				titles = append(titles, b.BookName)
+				years = append(years, b.Year)
+			}
+			if strings.Join(titles, ",") != strings.Join(tc.titles, ",") || !slices.Equal(years, tc.years) {
+				t.Errorf("catalogue %v %v, want %v %v", titles, years, tc.titles, tc.years)
+			}
+		})
+	}
+}
+
+func TestReadBooksCSVRejectsBadYear(t *testing.T) {
+	books, err := readBooksCSV(strings.NewReader("title,author,isbn,year\nEmma,Jane Austen,9780141439587,soon\n"))
+	if err == nil {
+		t.Fatalf("read %v from a row with an invalid year", books)
+	}
+}
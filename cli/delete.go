@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kushalpraja/library-api/models"
+)
+
+// undoWindow is how long a deletion can be undone
+const undoWindow = 10 * time.Second
+
+// deleteCandidatesMsg carries the books matching a title typed into the delete form
+type deleteCandidatesMsg []models.Book
+
+// deletedMsg reports which books were removed; err is set if some deletes failed
+type deletedMsg struct {
+	books  []models.Book
+	queued int // how many deletes were saved offline instead of sent
+	err    string
+}
+
+// undoTickMsg refreshes the undo countdown
+type undoTickMsg struct{}
+
+// contains the logic for finding the books a delete would remove
+func makeDeleteCandidatesRequest(title string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		books, _, err := searchBooks(ctx, title)
+		if err != nil {
+			return errorMsg(fmt.Sprintf("Request error: %v", err))
+		}
+
+		// search matches substrings, deletes match the exact title
+		var matches []models.Book
+		for _, b := range books {
+			if b.BookName == title {
+				matches = append(matches, b)
+			}
+		}
+		if len(matches) == 0 {
+			return errorMsg(fmt.Sprintf("No book titled %q", title))
+		}
+		return deleteCandidatesMsg(matches)
+	}
+}
+
+// contains the logic for deleting a confirmed set of books by id
+func makeDeleteBooksRequest(books []models.Book) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		var deleted []models.Book
+		var failures []string
+		queued := 0
+		for _, b := range books {
+			wasQueued, err := deleteBookByID(ctx, b)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s (#%d): %v", b.BookName, b.ID, err))
+				continue
+			}
+			if wasQueued {
+				queued++
+			}
+			deleted = append(deleted, b)
+		}
+		return deletedMsg{books: deleted, queued: queued, err: strings.Join(failures, "\n")}
+	}
+}
+
+// contains the logic for re-creating deleted books from their snapshot
+func makeRestoreRequest(books []models.Book) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		for _, b := range books {
+			if _, err := addBook(ctx, b); err != nil {
+				return errorMsg(fmt.Sprintf("Undo failed on %q: %v", b.BookName, err))
+			}
+		}
+		return responseMsg(fmt.Sprintf("Restored %d book(s)", len(books)))
+	}
+}
+
+func undoTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return undoTickMsg{} })
+}
+
+// confirmDelete shows the confirmation dialog for books
+func (m model) confirmDelete(books []models.Book) model {
+	m.state = StateConfirmDelete
+	m.pendingDelete = books
+	return m
+}
+
+func (m model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Confirm):
+		books := m.pendingDelete
+		m.pendingDelete = nil
+		m.state = StateLoading
+		return m, makeDeleteBooksRequest(books)
+	case key.Matches(msg, keys.Cancel):
+		m.pendingDelete = nil
+		m.state = StateMenu
+		return m, nil
+	}
+	return m, nil
+}
+
+// undoRemaining is the time left to undo the last deletion, zero once it expired
+func (m model) undoRemaining() time.Duration {
+	if len(m.undoBooks) == 0 {
+		return 0
+	}
+	return max(time.Until(m.undoDeadline), 0)
+}
+
+func (m model) viewConfirmDelete() string {
+	s := titleStyle.Render("Delete Book") + "\n\n"
+	s += fmt.Sprintf("The following %d book(s) will be deleted:\n\n", len(m.pendingDelete))
+
+	var lines []string
+	for _, b := range m.pendingDelete {
+		lines = append(lines, fmt.Sprintf("#%-5d %s — %s (ISBN %s)", b.ID, b.BookName, b.Author, strconv.Itoa(b.ISBN)))
+	}
+	s += errorStyle.Render(strings.Join(lines, "\n")) + "\n\n"
+
+	s += lipgloss.NewStyle().Bold(true).Render("Delete these books?") + "\n\n"
+	s += m.viewHelp()
+	return s
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kushalpraja/library-api/isbn"
+)
+
+// editFields are the book fields the edit form can change, as the API names them
+var editFields = []string{"Book_name", "Author", "ISBN"}
+
+// field status styles, set by applyTheme
+var fieldErrorStyle, fieldOKStyle lipgloss.Style
+
+// authorsMsg carries the distinct author names used for autocomplete
+type authorsMsg []string
+
+// contains the logic for collecting existing author names
+func makeAuthorsRequest() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		books, _, err := listBooks(ctx)
+		if err != nil {
+			// autocomplete is a convenience, the form works without it
+			return authorsMsg(nil)
+		}
+		seen := make(map[string]bool)
+		var authors []string
+		for _, b := range books {
+			if b.Author != "" && !seen[b.Author] {
+				seen[b.Author] = true
+				authors = append(authors, b.Author)
+			}
+		}
+		sort.Strings(authors)
+		return authorsMsg(authors)
+	}
+}
+
+func validateTitle(v string) string {
+	if strings.TrimSpace(v) == "" {
+		return "Title is required"
+	}
+	return ""
+}
+
+func validateAuthor(v string) string {
+	if strings.TrimSpace(v) == "" {
+		return "Author is required"
+	}
+	return ""
+}
+
+// validateISBN checks length and check digit as the user types; an empty
+// value is only an error when required is set
+func validateISBN(v string, required bool) string {
+	code := isbn.Normalize(v)
+	if code == "" {
+		if required {
+			return "ISBN is required"
+		}
+		return ""
+	}
+	for i, r := range code {
+		if !unicode.IsDigit(r) && !(r == 'X' && i == len(code)-1) {
+			return "ISBN may only contain digits"
+		}
+	}
+	if strings.HasSuffix(code, "X") {
+		return "ISBN-10s ending in X can't be stored, use the ISBN-13"
+	}
+	if len(code) != 10 && len(code) != 13 {
+		return fmt.Sprintf("ISBN must be 10 or 13 digits (%d so far)", len(code))
+	}
+	if !isbn.Valid(code) {
+		return "Check digit doesn't match, look for a typo"
+	}
+	return ""
+}
+
+// validateEditValue checks the new value against the selected field
+func validateEditValue(field, v string) string {
+	switch field {
+	case "ISBN":
+		return validateISBN(v, true)
+	case "Author":
+		return validateAuthor(v)
+	}
+	return validateTitle(v)
+}
+
+// addFormErrors returns the validation message for each add form input
+func (m model) addFormErrors() []string {
+	return []string{
+		validateTitle(m.bookNameInput.Value()),
+		validateAuthor(m.authorInput.Value()),
+		validateISBN(m.isbnInput.Value(), true),
+	}
+}
+
+// editFormErrors returns the validation message for each edit form input
+func (m model) editFormErrors() []string {
+	return []string{
+		validateTitle(m.titleInput.Value()),
+		"",
+		validateEditValue(editFields[m.fieldIndex], m.valueInput.Value()),
+	}
+}
+
+func hasErrors(errs []string) bool {
+	for _, e := range errs {
+		if e != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldStatus renders the line under an input. Empty inputs stay quiet
+// until the user has tried to submit, so a fresh form isn't all red.
+func (m model) fieldStatus(value, errText, okText string) string {
+	switch {
+	case errText != "" && (value != "" || m.submitted):
+		return "\n" + fieldErrorStyle.Render("✗ "+errText)
+	case errText == "" && okText != "" && value != "":
+		return "\n" + fieldOKStyle.Render("✓ "+okText)
+	}
+	return ""
+}
+
+// isbnOK describes a valid ISBN input
+func isbnOK(v string) string {
+	return fmt.Sprintf("valid ISBN-%d", len(isbn.Normalize(v)))
+}
+
+// viewFieldSelector renders the edit field choices with the current one highlighted
+func (m model) viewFieldSelector() string {
+	var parts []string
+	for i, f := range editFields {
+		if i == m.fieldIndex {
+			parts = append(parts, selectedStyle.Render("["+f+"]"))
+		} else {
+			parts = append(parts, " "+f+" ")
+		}
+	}
+	s := strings.Join(parts, " ")
+	if m.currentInput == 1 {
+		s = "> " + s + "  " + helpStyles.ShortDesc.Render(keys.FieldLeft.Help().Key+"/"+keys.FieldRight.Help().Key+" to change")
+	} else {
+		s = "  " + s
+	}
+	return s
+}
+
+// selectEditField switches the edit form to another field and adapts the value input
+func (m model) selectEditField(i int) model {
+	m.fieldIndex = (i + len(editFields)) % len(editFields)
+	switch editFields[m.fieldIndex] {
+	case "ISBN":
+		m.valueInput.Placeholder = "Enter new ISBN-10 or ISBN-13"
+		m.valueInput.ShowSuggestions = false
+	case "Author":
+		m.valueInput.Placeholder = "Enter new author"
+		m.valueInput.ShowSuggestions = true
+	default:
+		m.valueInput.Placeholder = "Enter new title"
+		m.valueInput.ShowSuggestions = false
+	}
+	return m
+}
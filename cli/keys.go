@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap holds every key binding in the TUI. Bindings can be remapped by
+// name from the config file, see keyNames.
+type keyMap struct {
+	Up        key.Binding
+	Down      key.Binding
+	PageUp    key.Binding
+	PageDown  key.Binding
+	Top       key.Binding
+	Bottom    key.Binding
+	Select    key.Binding
+	Back      key.Binding
+	Quit      key.Binding
+	ForceQuit key.Binding
+	Help      key.Binding
+
+	NextField        key.Binding
+	PrevField        key.Binding
+	FieldLeft        key.Binding
+	FieldRight       key.Binding
+	AcceptSuggestion key.Binding
+	Submit           key.Binding
+	Lookup           key.Binding
+
+	Filter  key.Binding
+	Sort    key.Binding
+	Mark    key.Binding
+	Edit    key.Binding
+	Delete  key.Binding
+	Refresh key.Binding
+
+	Confirm key.Binding
+	Cancel  key.Binding
+	Undo    key.Binding
+}
+
+var keys = defaultKeyMap()
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Up:        key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+		Down:      key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+		PageUp:    key.NewBinding(key.WithKeys("pgup", "ctrl+u"), key.WithHelp("pgup", "page up")),
+		PageDown:  key.NewBinding(key.WithKeys("pgdown", "ctrl+d"), key.WithHelp("pgdn", "page down")),
+		Top:       key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("g/home", "first")),
+		Bottom:    key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("G/end", "last")),
+		Select:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+		Back:      key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Quit:      key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+		ForceQuit: key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+		Help:      key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more keys")),
+
+		NextField:  key.NewBinding(key.WithKeys("tab", "ctrl+n"), key.WithHelp("tab", "next field")),
+		PrevField:  key.NewBinding(key.WithKeys("shift+tab", "ctrl+p"), key.WithHelp("shift+tab", "prev field")),
+		FieldLeft:  key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "prev choice")),
+		FieldRight: key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "next choice")),
+		// tab is taken by field navigation, so suggestions are accepted with →
+		AcceptSuggestion: key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "accept suggestion")),
+		Submit:           key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "submit")),
+		Lookup:           key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "fetch by ISBN")),
+
+		Filter:  key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+		Sort:    key.NewBinding(key.WithKeys("1", "2", "3", "4"), key.WithHelp("1-4", "sort by column")),
+		Mark:    key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select row")),
+		Edit:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+		Delete:  key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+		Refresh: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+
+		Confirm: key.NewBinding(key.WithKeys("y", "enter"), key.WithHelp("y/enter", "confirm")),
+		Cancel:  key.NewBinding(key.WithKeys("n", "esc"), key.WithHelp("n/esc", "cancel")),
+		Undo:    key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo")),
+	}
+}
+
+// keyNames maps the names used in the config file to bindings
+func (k *keyMap) keyNames() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up": &k.Up, "down": &k.Down, "page_up": &k.PageUp, "page_down": &k.PageDown,
+		"top": &k.Top, "bottom": &k.Bottom, "select": &k.Select, "back": &k.Back,
+		"quit": &k.Quit, "force_quit": &k.ForceQuit, "help": &k.Help,
+		"next_field": &k.NextField, "prev_field": &k.PrevField,
+		"field_left": &k.FieldLeft, "field_right": &k.FieldRight,
+		"accept_suggestion": &k.AcceptSuggestion, "submit": &k.Submit, "lookup": &k.Lookup,
+		"filter": &k.Filter, "sort": &k.Sort, "mark": &k.Mark, "edit": &k.Edit,
+		"delete": &k.Delete, "refresh": &k.Refresh,
+		"confirm": &k.Confirm, "cancel": &k.Cancel, "undo": &k.Undo,
+	}
+}
+
+// override rebinds the named actions, keeping their help descriptions
+func (k *keyMap) override(bindings map[string][]string) error {
+	names := k.keyNames()
+	var unknown []string
+	for name, keyList := range bindings {
+		b, ok := names[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if len(keyList) == 0 {
+			continue
+		}
+		*b = key.NewBinding(key.WithKeys(keyList...), key.WithHelp(strings.Join(keyList, "/"), b.Help().Desc))
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown key binding(s): %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// sortColumn returns which column a sort key selects, by its position in the binding
+func (k keyMap) sortColumn(pressed string) int {
+	for i, s := range k.Sort.Keys() {
+		if s == pressed {
+			return i
+		}
+	}
+	return -1
+}
+
+// helpKeys is the help.KeyMap for one screen
+type helpKeys struct {
+	short []key.Binding
+	full  [][]key.Binding
+}
+
+func (h helpKeys) ShortHelp() []key.Binding  { return h.short }
+func (h helpKeys) FullHelp() [][]key.Binding { return h.full }
+
+// helpStyles are set by applyTheme
+var helpStyles help.Styles
+
+// helpKeys returns the bindings relevant to the current screen
+func (m model) helpKeys() helpKeys {
+	switch m.state {
+	case StateMenu:
+		return helpKeys{
+			short: []key.Binding{keys.Up, keys.Down, keys.Select, keys.Help, keys.Quit},
+			full:  [][]key.Binding{{keys.Up, keys.Down, keys.Select}, {keys.Help, keys.Quit}},
+		}
+	case StateAddBook:
+		return helpKeys{
+			short: []key.Binding{keys.NextField, keys.PrevField, keys.AcceptSuggestion, keys.Lookup, keys.Submit, keys.Back, keys.ForceQuit},
+		}
+	case StateDeleteBook:
+		return helpKeys{short: []key.Binding{keys.Submit, keys.Back, keys.ForceQuit}}
+	case StateEditBook:
+		choose := key.NewBinding(key.WithKeys(append(keys.FieldLeft.Keys(), keys.FieldRight.Keys()...)...), key.WithHelp(keys.FieldLeft.Help().Key+"/"+keys.FieldRight.Help().Key, "choose field"))
+		return helpKeys{
+			short: []key.Binding{keys.NextField, keys.PrevField, choose, keys.Submit, keys.Back, keys.ForceQuit},
+		}
+	case StateList:
+		return helpKeys{
+			short: []key.Binding{keys.Up, keys.Down, keys.Filter, keys.Mark, keys.Edit, keys.Delete, keys.Help, keys.Back},
+			full: [][]key.Binding{
+				{keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Top, keys.Bottom},
+				{keys.Filter, keys.Sort, keys.Refresh},
+				{keys.Mark, keys.Edit, keys.Delete},
+				{keys.Help, keys.Back, keys.Quit},
+			},
+		}
+	case StateConfirmDelete:
+		return helpKeys{short: []key.Binding{keys.Confirm, keys.Cancel, keys.ForceQuit}}
+	case StateShowResponse:
+		back := key.NewBinding(key.WithKeys(keys.Select.Keys()...), key.WithHelp(keys.Select.Help().Key, "back to menu"))
+		short := []key.Binding{back, keys.Quit}
+		if m.undoRemaining() > 0 {
+			short = append([]key.Binding{keys.Undo}, short...)
+		}
+		return helpKeys{short: short}
+	}
+	return helpKeys{short: []key.Binding{keys.Quit}}
+}
+
+// viewHelp renders the help line, or the full help when toggled with ?
+func (m model) viewHelp() string {
+	h := m.help
+	h.Styles = helpStyles
+	hk := m.helpKeys()
+	if hk.full == nil {
+		h.ShowAll = false
+	}
+	return h.View(hk)
+}
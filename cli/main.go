@@ -1,66 +1,58 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kushalpraja/library-api/client"
+	"github.com/kushalpraja/library-api/isbn"
+	"github.com/kushalpraja/library-api/models"
 )
 
-// Styles
+// Styles, set from the configured theme by applyTheme
 var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Background(lipgloss.Color("#7D56F4")).
-			Padding(0, 1)
-
-	selectedStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#7D56F4"))
-
-	responseStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#04B575")).
-			Border(lipgloss.RoundedBorder()).
-			Padding(1)
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF5F87")).
-			Border(lipgloss.RoundedBorder()).
-			Padding(1)
-
-	inputStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			Padding(1)
+	titleStyle    lipgloss.Style
+	selectedStyle lipgloss.Style
+	responseStyle lipgloss.Style
+	errorStyle    lipgloss.Style
+	inputStyle    lipgloss.Style
 )
 
-// Book represents a book structure
-type Book struct {
-	BookName string `json:"Book_name"`
-	Author   string `json:"Author"`
-	ISBN     int    `json:"ISBN"`
-}
+// serverURL is the address of the library API
+var serverURL = envOr("LIBRARY_API_URL", "http://localhost:8080")
 
-// EditRequest represents an edit request
-type EditRequest struct {
-	Title string `json:"title"`
-	Field string `json:"field"`
-	Value string `json:"value"`
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return strings.TrimRight(value, "/")
+	}
+	return fallback
 }
 
-// DeleteRequest represents a delete request
-type DeleteRequest struct {
-	Title string `json:"title"`
+// api is the client used for every request. It is rebuilt by the
+// subcommands once --server has been parsed.
+var api = newAPIClient()
+
+func newAPIClient() *client.Client {
+	return client.New(serverURL,
+		client.WithTimeout(requestTimeout),
+		client.WithAPIKey(os.Getenv("LIBRARY_API_KEY")),
+		client.WithUserAgent("library-api-cli"),
+	)
 }
 
+// requestTimeout bounds each call made from the TUI and subcommands
+const requestTimeout = 10 * time.Second
+
 // State represents the current state of the application
 type State int
 
@@ -71,6 +63,8 @@ const (
 	StateEditBook
 	StateLoading
 	StateShowResponse
+	StateList
+	StateConfirmDelete
 )
 
 // Model represents the state of the application
@@ -88,12 +82,35 @@ type model struct {
 
 	// Input fields for delete/edit
 	titleInput textinput.Model
-	fieldInput textinput.Model
+	fieldIndex int
 	valueInput textinput.Model
 
+	// editTarget is the book the edit form was opened on from the list.
+	// While the title is left as it is, the edit goes to that book by id
+	// rather than to every book with the title.
+	editTarget models.Book
+
 	// Current input focus
 	currentInput int
 	maxInputs    int
+
+	// Set once the user tried to submit, so required field errors show
+	submitted bool
+
+	// Status line shown under the add form (e.g. ISBN lookup results)
+	formMsg string
+
+	// Table view of the book list
+	list bookList
+
+	// Books awaiting delete confirmation, and the last deleted books
+	// which can be restored until undoDeadline
+	pendingDelete []models.Book
+	undoBooks     []models.Book
+	undoDeadline  time.Time
+
+	// Key help shown at the bottom of each screen, ? expands it
+	help help.Model
 }
 
 // initialModel initializes the model with default values
@@ -109,6 +126,8 @@ func initialModel() model {
 	authorInput.Placeholder = "Enter author name"
 	authorInput.CharLimit = 100
 	authorInput.Width = 50
+	authorInput.ShowSuggestions = true
+	authorInput.KeyMap.AcceptSuggestion = keys.AcceptSuggestion
 
 	isbnInput := textinput.New()
 	isbnInput.Placeholder = "Enter ISBN (numbers only)"
@@ -120,15 +139,11 @@ func initialModel() model {
 	titleInput.CharLimit = 100
 	titleInput.Width = 50
 
-	fieldInput := textinput.New()
-	fieldInput.Placeholder = "Enter field to edit (Book_name, Author, ISBN)"
-	fieldInput.CharLimit = 50
-	fieldInput.Width = 50
-
 	valueInput := textinput.New()
 	valueInput.Placeholder = "Enter new value to update"
 	valueInput.CharLimit = 100
 	valueInput.Width = 50
+	valueInput.KeyMap.AcceptSuggestion = keys.AcceptSuggestion
 
 	return model{
 		state: StateMenu,
@@ -142,8 +157,8 @@ func initialModel() model {
 		authorInput:   authorInput,
 		isbnInput:     isbnInput,
 		titleInput:    titleInput,
-		fieldInput:    fieldInput,
 		valueInput:    valueInput,
+		help:          help.New(),
 	}
 }
 
@@ -156,110 +171,106 @@ func (m model) Init() tea.Cmd {
 type responseMsg string
 type errorMsg string
 
+// lookupMsg carries the prefilled fields returned by an ISBN lookup
+type lookupMsg struct {
+	book models.CreateBookRequest
+	err  string
+}
+
 // contains the logic for making a list request
 func makeListRequest() tea.Cmd {
 	return func() tea.Msg {
-		resp, err := http.Get("http://localhost:8080/books/list")
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Request error: %v", err))
-		}
-		defer resp.Body.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
 
-		bodyBytes, err := io.ReadAll(resp.Body)
+		books, offline, err := listBooks(ctx)
 		if err != nil {
-			return errorMsg(fmt.Sprintf("Read error: %v", err))
+			return errorMsg(fmt.Sprintf("Request error: %v", err))
 		}
-
-		return responseMsg(string(bodyBytes))
+		return booksMsg{books: books, offline: offline}
 	}
 }
 
 // contains the logic for making an add request
-func makeAddRequest(book Book) tea.Cmd {
+func makeAddRequest(book models.Book) tea.Cmd {
 	return func() tea.Msg {
-		jsonData, err := json.Marshal(book)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("JSON marshal error: %v", err))
-		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
 
-		resp, err := http.Post("http://localhost:8080/books/add", "application/json", bytes.NewBuffer(jsonData))
+		queued, err := addBook(ctx, book)
 		if err != nil {
 			return errorMsg(fmt.Sprintf("Request error: %v", err))
 		}
-		defer resp.Body.Close()
-
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Read error: %v", err))
+		if queued {
+			return responseMsg("Server unreachable: the book was saved offline and will be added once it's back")
 		}
-
-		return responseMsg(string(bodyBytes))
+		return responseMsg("Book added successfully")
 	}
 }
 
-// contains the logic for making a delete request
-func makeDeleteRequest(title string) tea.Cmd {
+// contains the logic for fetching book metadata by ISBN
+func makeLookupRequest(isbn string) tea.Cmd {
 	return func() tea.Msg {
-		deleteReq := DeleteRequest{Title: title}
-		jsonData, err := json.Marshal(deleteReq)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("JSON marshal error: %v", err))
-		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
 
-		client := &http.Client{}
-		req, err := http.NewRequest("DELETE", "http://localhost:8080/books/delete", bytes.NewBuffer(jsonData))
+		resp, err := api.LookupISBN(ctx, isbn)
 		if err != nil {
-			return errorMsg(fmt.Sprintf("Request creation error: %v", err))
+			return lookupMsg{err: err.Error()}
 		}
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Request error: %v", err))
-		}
-		defer resp.Body.Close()
-
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Read error: %v", err))
-		}
-
-		return responseMsg(string(bodyBytes))
+		return lookupMsg{book: resp.Book}
 	}
 }
 
 // contains the logic for making an edit request
 func makeEditRequest(title, field, value string) tea.Cmd {
 	return func() tea.Msg {
-		editReq := EditRequest{
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		editReq := models.EditRequest{
 			Title: title,
 			Field: field,
 			Value: value,
 		}
-		jsonData, err := json.Marshal(editReq)
+		queued, err := editBook(ctx, editReq)
 		if err != nil {
-			return errorMsg(fmt.Sprintf("JSON marshal error: %v", err))
+			return errorMsg(fmt.Sprintf("Request error: %v", err))
 		}
-
-		client := &http.Client{}
-		req, err := http.NewRequest("PATCH", "http://localhost:8080/books/edit", bytes.NewBuffer(jsonData))
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Request creation error: %v", err))
+		if queued {
+			return responseMsg("Server unreachable: the edit was saved offline and will be applied once it's back")
 		}
-		req.Header.Set("Content-Type", "application/json")
+		return responseMsg("Book updated")
+	}
+}
 
-		resp, err := client.Do(req)
+// makeUpdateRequest changes one field of a single book, by its id
+func makeUpdateRequest(book models.Book, field, value string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		var update models.UpdateBookRequest
+		switch field {
+		case "Book_name":
+			update.BookName = &value
+		case "Author":
+			update.Author = &value
+		case "ISBN":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return errorMsg("Request error: ISBN " + value + " can't be stored as a number")
+			}
+			update.ISBN = &n
+		}
+		queued, err := updateBook(ctx, book, update)
 		if err != nil {
 			return errorMsg(fmt.Sprintf("Request error: %v", err))
 		}
-		defer resp.Body.Close()
-
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Read error: %v", err))
+		if queued {
+			return responseMsg("Server unreachable: the edit was saved offline and will be applied once it's back")
 		}
-
-		return responseMsg(string(bodyBytes))
+		return responseMsg("Book updated")
 	}
 }
 
@@ -280,9 +291,6 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.titleInput, cmd = m.titleInput.Update(msg)
 	cmds = append(cmds, cmd)
 
-	m.fieldInput, cmd = m.fieldInput.Update(msg)
-	cmds = append(cmds, cmd)
-
 	m.valueInput, cmd = m.valueInput.Update(msg)
 	cmds = append(cmds, cmd)
 
@@ -305,16 +313,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			newModel, newCmd := m.updateEditBook(msg)
 			cmds = append(cmds, newCmd)
 			return newModel, tea.Batch(cmds...)
+		case StateList:
+			newModel, newCmd := m.updateList(msg)
+			cmds = append(cmds, newCmd)
+			return newModel, tea.Batch(cmds...)
+		case StateConfirmDelete:
+			newModel, newCmd := m.updateConfirmDelete(msg)
+			cmds = append(cmds, newCmd)
+			return newModel, tea.Batch(cmds...)
 		case StateLoading:
-			if msg.String() == "q" || msg.String() == "ctrl+c" {
+			if key.Matches(msg, keys.Quit) {
 				return m, tea.Quit
 			}
 			return m, tea.Batch(cmds...)
 		case StateShowResponse:
-			if msg.String() == "q" || msg.String() == "ctrl+c" {
+			if key.Matches(msg, keys.Quit) {
 				return m, tea.Quit
 			}
-			if msg.String() == "enter" || msg.String() == "esc" {
+			if key.Matches(msg, keys.Undo) && m.undoRemaining() > 0 {
+				books := m.undoBooks
+				m.undoBooks = nil
+				m.state = StateLoading
+				return m, makeRestoreRequest(books)
+			}
+			if key.Matches(msg, keys.Select, keys.Back) {
 				m.state = StateMenu
 				m.response = ""
 				m.errMsg = ""
@@ -329,6 +351,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.errMsg = ""
 		return m, tea.Batch(cmds...)
 
+	case deleteCandidatesMsg:
+		return m.confirmDelete(msg), tea.Batch(cmds...)
+
+	case deletedMsg:
+		m.state = StateShowResponse
+		m.response = ""
+		if len(msg.books) > 0 {
+			m.response = fmt.Sprintf("Deleted %d book(s)", len(msg.books))
+			if msg.queued > 0 {
+				m.response += fmt.Sprintf(", %d saved offline until the server is reachable", msg.queued)
+			}
+			m.undoBooks = msg.books
+			m.undoDeadline = time.Now().Add(undoWindow)
+			cmds = append(cmds, undoTick())
+		}
+		m.errMsg = msg.err
+		return m, tea.Batch(cmds...)
+
+	case undoTickMsg:
+		// keep ticking so the countdown redraws until the window closes
+		if m.undoRemaining() > 0 {
+			return m, tea.Batch(append(cmds, undoTick())...)
+		}
+		m.undoBooks = nil
+		return m, tea.Batch(cmds...)
+
+	case authorsMsg:
+		m.authorInput.SetSuggestions(msg)
+		m.valueInput.SetSuggestions(msg)
+		return m, tea.Batch(cmds...)
+
+	case booksMsg:
+		m.state = StateList
+		m.list = newBookList(msg.books)
+		m.list.offline = msg.offline
+		return m, tea.Batch(cmds...)
+
+	case lookupMsg:
+		if m.state != StateAddBook {
+			return m, tea.Batch(cmds...)
+		}
+		if msg.err != "" {
+			m.formMsg = "Lookup failed: " + msg.err
+			return m, tea.Batch(cmds...)
+		}
+		m.bookNameInput.SetValue(msg.book.BookName)
+		m.authorInput.SetValue(msg.book.Author)
+		if msg.book.ISBN != 0 {
+			m.isbnInput.SetValue(strconv.Itoa(msg.book.ISBN))
+		}
+		m.formMsg = "Fields filled from ISBN lookup"
+		return m, tea.Batch(cmds...)
+
 	case errorMsg:
 		m.state = StateShowResponse
 		m.errMsg = string(msg)
@@ -340,18 +415,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", "ctrl+c":
+	switch {
+	case key.Matches(msg, keys.Quit):
 		return m, tea.Quit
-	case "up", "k":
+	case key.Matches(msg, keys.Help):
+		m.help.ShowAll = !m.help.ShowAll
+	case key.Matches(msg, keys.Up):
 		if m.cursor > 0 {
 			m.cursor--
 		}
-	case "down", "j":
+	case key.Matches(msg, keys.Down):
 		if m.cursor < len(m.choices)-1 {
 			m.cursor++
 		}
-	case "enter":
+	case key.Matches(msg, keys.Select):
 		switch m.choices[m.cursor] {
 		case "List Books":
 			m.state = StateLoading
@@ -366,76 +443,80 @@ func (m model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.bookNameInput.SetValue("")
 			m.authorInput.SetValue("")
 			m.isbnInput.SetValue("")
-			return m, textinput.Blink
+			m.formMsg = ""
+			m.submitted = false
+			return m, tea.Batch(textinput.Blink, makeAuthorsRequest())
 		case "Delete Book":
-			m.state = StateDeleteBook
-			m.titleInput.Focus()
-			m.titleInput.SetValue("")
-			return m, textinput.Blink
+			return m.startDelete(), textinput.Blink
 		case "Edit Book":
-			m.state = StateEditBook
-			m.currentInput = 0
-			m.maxInputs = 3
-			m.titleInput.Focus()
-			m.fieldInput.Blur()
-			m.valueInput.Blur()
-			m.titleInput.SetValue("")
-			m.fieldInput.SetValue("")
-			m.valueInput.SetValue("")
-			return m, textinput.Blink
+			return m.startEdit(), tea.Batch(textinput.Blink, makeAuthorsRequest())
 		}
 	}
 	return m, nil
 }
 
+// startDelete opens an empty delete form
+func (m model) startDelete() model {
+	m.state = StateDeleteBook
+	m.titleInput.Focus()
+	m.titleInput.SetValue("")
+	return m
+}
+
+// startEdit opens an empty edit form with the title focused
+func (m model) startEdit() model {
+	m.state = StateEditBook
+	m.currentInput = 0
+	m.maxInputs = 3
+	m.titleInput.Focus()
+	m.valueInput.Blur()
+	m.titleInput.SetValue("")
+	m.valueInput.SetValue("")
+	m.submitted = false
+	m.editTarget = models.Book{}
+	return m.selectEditField(0)
+}
+
 func (m model) updateAddBook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c":
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
 		return m, tea.Quit
-	case "esc":
+	case key.Matches(msg, keys.Back):
 		m.state = StateMenu
 		return m, nil
-	case "tab":
+	case key.Matches(msg, keys.NextField):
 		m.currentInput++
 		if m.currentInput >= m.maxInputs {
 			m.currentInput = 0
 		}
 		return m.updateInputFocus(), nil
-	case "shift+tab":
+	case key.Matches(msg, keys.PrevField):
 		m.currentInput--
 		if m.currentInput < 0 {
 			m.currentInput = m.maxInputs - 1
 		}
 		return m.updateInputFocus(), nil
-	case "ctrl+n":
-		// Alternative way to move to next input
-		m.currentInput++
-		if m.currentInput >= m.maxInputs {
-			m.currentInput = 0
+	case key.Matches(msg, keys.Lookup):
+		// Fetch title and author for the entered ISBN
+		code := strings.TrimSpace(m.isbnInput.Value())
+		if code == "" {
+			m.formMsg = "Enter an ISBN to fetch by"
+			return m, nil
 		}
-		return m.updateInputFocus(), nil
-	case "ctrl+p":
-		// Alternative way to move to previous input
-		m.currentInput--
-		if m.currentInput < 0 {
-			m.currentInput = m.maxInputs - 1
-		}
-		return m.updateInputFocus(), nil
-	case "ctrl+s":
-		// Submit the form with Ctrl+S
-		isbn, err := strconv.Atoi(m.isbnInput.Value())
-		if err != nil && m.isbnInput.Value() != "" {
-			return m, func() tea.Msg { return errorMsg("ISBN must be a valid number") }
-		}
-
-		book := Book{
-			BookName: m.bookNameInput.Value(),
-			Author:   m.authorInput.Value(),
-			ISBN:     isbn,
+		m.formMsg = "Looking up ISBN " + code + "..."
+		return m, makeLookupRequest(code)
+	case key.Matches(msg, keys.Submit):
+		// Submit the form with Ctrl+S, staying on it while any field is invalid
+		m.submitted = true
+		if hasErrors(m.addFormErrors()) {
+			return m, nil
 		}
 
-		if book.BookName == "" || book.Author == "" {
-			return m, func() tea.Msg { return errorMsg("Book name and author are required") }
+		isbnValue, _ := strconv.Atoi(isbn.Normalize(m.isbnInput.Value()))
+		book := models.Book{
+			BookName: strings.TrimSpace(m.bookNameInput.Value()),
+			Author:   strings.TrimSpace(m.authorInput.Value()),
+			ISBN:     isbnValue,
 		}
 
 		m.state = StateLoading
@@ -445,77 +526,65 @@ func (m model) updateAddBook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateDeleteBook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c":
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
 		return m, tea.Quit
-	case "esc":
+	case key.Matches(msg, keys.Back):
 		m.state = StateMenu
 		return m, nil
-	case "ctrl+s":
-		// Submit with Ctrl+S
+	case key.Matches(msg, keys.Submit):
+		// Look up what would be deleted and ask for confirmation first
 		if m.titleInput.Value() == "" {
 			return m, func() tea.Msg { return errorMsg("Title is required") }
 		}
 		m.state = StateLoading
-		return m, makeDeleteRequest(m.titleInput.Value())
+		return m, makeDeleteCandidatesRequest(m.titleInput.Value())
 	}
 	return m, nil
 }
 
 func (m model) updateEditBook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c":
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
 		return m, tea.Quit
-	case "esc":
+	case key.Matches(msg, keys.Back):
 		m.state = StateMenu
 		return m, nil
-	case "tab":
+	case key.Matches(msg, keys.NextField):
 		m.currentInput++
 		if m.currentInput >= m.maxInputs {
 			m.currentInput = 0
 		}
 		return m.updateEditInputFocus(), nil
-	case "shift+tab":
+	case key.Matches(msg, keys.PrevField):
 		m.currentInput--
 		if m.currentInput < 0 {
 			m.currentInput = m.maxInputs - 1
 		}
 		return m.updateEditInputFocus(), nil
-	case "ctrl+n":
-		// Alternative way to move to next input
-		m.currentInput++
-		if m.currentInput >= m.maxInputs {
-			m.currentInput = 0
-		}
-		return m.updateEditInputFocus(), nil
-	case "ctrl+p":
-		// Alternative way to move to previous input
-		m.currentInput--
-		if m.currentInput < 0 {
-			m.currentInput = m.maxInputs - 1
-		}
-		return m.updateEditInputFocus(), nil
-	case "ctrl+s":
-		// Submit the form with Ctrl+S
-		if m.titleInput.Value() == "" || m.fieldInput.Value() == "" || m.valueInput.Value() == "" {
-			return m, func() tea.Msg { return errorMsg("All fields are required") }
-		}
-
-		validFields := []string{"Book_name", "Author", "ISBN"}
-		fieldValid := false
-		for _, field := range validFields {
-			if m.fieldInput.Value() == field {
-				fieldValid = true
-				break
-			}
+	case m.currentInput == 1 && key.Matches(msg, keys.FieldLeft):
+		// Cycle the field selector while it has focus
+		return m.selectEditField(m.fieldIndex - 1), nil
+	case m.currentInput == 1 && key.Matches(msg, keys.FieldRight):
+		return m.selectEditField(m.fieldIndex + 1), nil
+	case key.Matches(msg, keys.Submit):
+		// Submit the form with Ctrl+S, staying on it while any field is invalid
+		m.submitted = true
+		if hasErrors(m.editFormErrors()) {
+			return m, nil
 		}
 
-		if !fieldValid {
-			return m, func() tea.Msg { return errorMsg("Field must be one of: Book_name, Author, ISBN") }
+		field := editFields[m.fieldIndex]
+		value := strings.TrimSpace(m.valueInput.Value())
+		if field == "ISBN" {
+			value = isbn.Normalize(value)
 		}
 
 		m.state = StateLoading
-		return m, makeEditRequest(m.titleInput.Value(), m.fieldInput.Value(), m.valueInput.Value())
+		if m.editTarget.ID != 0 && m.titleInput.Value() == m.editTarget.BookName {
+			return m, makeUpdateRequest(m.editTarget, field, value)
+		}
+		return m, makeEditRequest(m.titleInput.Value(), field, value)
 	}
 	return m, nil
 }
@@ -539,14 +608,12 @@ func (m model) updateInputFocus() model {
 
 func (m model) updateEditInputFocus() model {
 	m.titleInput.Blur()
-	m.fieldInput.Blur()
 	m.valueInput.Blur()
 
+	// input 1 is the field selector, which isn't a text input
 	switch m.currentInput {
 	case 0:
 		m.titleInput.Focus()
-	case 1:
-		m.fieldInput.Focus()
 	case 2:
 		m.valueInput.Focus()
 	}
@@ -568,6 +635,10 @@ func (m model) View() string {
 		return m.viewLoading()
 	case StateShowResponse:
 		return m.viewResponse()
+	case StateList:
+		return m.viewList()
+	case StateConfirmDelete:
+		return m.viewConfirmDelete()
 	}
 	return ""
 }
@@ -585,18 +656,27 @@ func (m model) viewMenu() string {
 		s += fmt.Sprintf("%s%s\n", cursor, choice)
 	}
 
-	s += "\n" + lipgloss.NewStyle().Faint(true).Render("↑/↓: navigate • enter: select • q: quit")
+	if notice := pendingNotice(); notice != "" {
+		s += "\n" + selectedStyle.Render(notice) + "\n"
+	}
+
+	s += "\n" + m.viewHelp()
 	return s
 }
 
 func (m model) viewAddBook() string {
 	s := titleStyle.Render("Add New Book") + "\n\n"
 
-	s += inputStyle.Render("Book Name:\n"+m.bookNameInput.View()) + "\n\n"
-	s += inputStyle.Render("Author:\n"+m.authorInput.View()) + "\n\n"
-	s += inputStyle.Render("ISBN:\n"+m.isbnInput.View()) + "\n\n"
+	errs := m.addFormErrors()
+	s += inputStyle.Render("Book Name:\n"+m.bookNameInput.View()+m.fieldStatus(m.bookNameInput.Value(), errs[0], "")) + "\n\n"
+	s += inputStyle.Render("Author:\n"+m.authorInput.View()+m.fieldStatus(m.authorInput.Value(), errs[1], "")) + "\n\n"
+	s += inputStyle.Render("ISBN:\n"+m.isbnInput.View()+m.fieldStatus(m.isbnInput.Value(), errs[2], isbnOK(m.isbnInput.Value()))) + "\n\n"
 
-	s += lipgloss.NewStyle().Faint(true).Render("tab: next field • shift+tab: prev field • ctrl+s: submit • esc: back • ctrl+c: quit")
+	if m.formMsg != "" {
+		s += selectedStyle.Render(m.formMsg) + "\n\n"
+	}
+
+	s += m.viewHelp()
 	return s
 }
 
@@ -605,25 +685,34 @@ func (m model) viewDeleteBook() string {
 
 	s += inputStyle.Render("Book Title:\n"+m.titleInput.View()) + "\n\n"
 
-	s += lipgloss.NewStyle().Faint(true).Render("ctrl+s: delete • esc: back • ctrl+c: quit")
+	s += m.viewHelp()
 	return s
 }
 
 func (m model) viewEditBook() string {
 	s := titleStyle.Render("Edit Book") + "\n\n"
 
-	s += inputStyle.Render("Book Title:\n"+m.titleInput.View()) + "\n\n"
-	s += inputStyle.Render("Field to Edit (Book_name/Author/ISBN):\n"+m.fieldInput.View()) + "\n\n"
-	s += inputStyle.Render("New Value:\n"+m.valueInput.View()) + "\n\n"
+	errs := m.editFormErrors()
+	valueOK := ""
+	if editFields[m.fieldIndex] == "ISBN" {
+		valueOK = isbnOK(m.valueInput.Value())
+	}
+	titleOK := ""
+	if m.editTarget.ID != 0 && m.titleInput.Value() == m.editTarget.BookName {
+		titleOK = fmt.Sprintf("only book #%d", m.editTarget.ID)
+	}
+	s += inputStyle.Render("Book Title:\n"+m.titleInput.View()+m.fieldStatus(m.titleInput.Value(), errs[0], titleOK)) + "\n\n"
+	s += inputStyle.Render("Field to Edit:\n"+m.viewFieldSelector()) + "\n\n"
+	s += inputStyle.Render("New Value:\n"+m.valueInput.View()+m.fieldStatus(m.valueInput.Value(), errs[2], valueOK)) + "\n\n"
 
-	s += lipgloss.NewStyle().Faint(true).Render("tab: next field • shift+tab: prev field • ctrl+s: submit • esc: back • ctrl+c: quit")
+	s += m.viewHelp()
 	return s
 }
 
 func (m model) viewLoading() string {
 	s := titleStyle.Render("Book Management System") + "\n\n"
 	s += "⏳ Processing request...\n\n"
-	s += lipgloss.NewStyle().Faint(true).Render("q: quit")
+	s += m.viewHelp()
 	return s
 }
 
@@ -640,7 +729,11 @@ func (m model) viewResponse() string {
 		s += errorStyle.Render(m.errMsg) + "\n\n"
 	}
 
-	s += lipgloss.NewStyle().Faint(true).Render("enter: back to menu • q: quit")
+	if remaining := m.undoRemaining(); remaining > 0 {
+		s += selectedStyle.Render(fmt.Sprintf("%s: undo delete (%ds left)", keys.Undo.Help().Key, int(remaining.Round(time.Second).Seconds()))) + "\n\n"
+	}
+
+	s += m.viewHelp()
 	return s
 }
 
@@ -658,6 +751,14 @@ func (m model) formatResponse(response string) string {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	if err := loadTUIConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: TUI config: %v\n", err)
+	}
+
 	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("💥 Error: %v\n", err)
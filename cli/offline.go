@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kushalpraja/library-api/client"
+	"github.com/kushalpraja/library-api/models"
+)
+
+// Kinds of write that can be queued while offline
+const (
+	opAdd      = "add"
+	opEdit     = "edit"
+	opDelete   = "delete"
+	opDeleteID = "delete_id"
+	opUpdate   = "update"
+)
+
+// queuedOp is a write made while the server was unreachable. Base holds the
+// records the write expected to find, so replay can spot server-side changes
+// by their update times.
+type queuedOp struct {
+	Kind     string             `json:"kind"`
+	Book     models.Book        `json:"book,omitzero"`
+	Edit     models.EditRequest `json:"edit,omitzero"`
+	Title    string             `json:"title,omitempty"`
+	Base     []models.Book      `json:"base,omitempty"`
+	QueuedAt time.Time          `json:"queued_at"`
+	// IdempotencyKey is kept with a queued add so a replay whose response
+	// was lost can't create the book twice
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Update holds the fields an update by id changes in Book
+	Update models.UpdateBookRequest `json:"update,omitzero"`
+}
+
+// conflict is a queued write that was not applied during replay
+type conflict struct {
+	Op     queuedOp      `json:"op"`
+	Reason string        `json:"reason"`
+	Server []models.Book `json:"server,omitempty"`
+}
+
+// syncReport summarises a replay of the queue
+type syncReport struct {
+	Applied   int        `json:"applied"`
+	Conflicts []conflict `json:"conflicts"`
+	Pending   int        `json:"pending"`
+}
+
+// offlineStore is the local copy of the catalogue plus the writes made while
+// the server was unreachable, kept in a JSON file in the user cache dir
+type offlineStore struct {
+	mu   sync.Mutex
+	path string
+
+	// counts mirrored on every save so views can read them without the lock
+	queued    atomic.Int64
+	conflicts atomic.Int64
+	syncedAt  atomic.Int64
+
+	Books      []models.Book `json:"books"`
+	SyncedAt   time.Time     `json:"synced_at"`
+	Queue      []queuedOp    `json:"queue"`
+	Conflicts  []conflict    `json:"conflicts"`
+	NextTempID int64         `json:"next_temp_id"`
+}
+
+var (
+	store     *offlineStore
+	storeOnce sync.Once
+)
+
+// localStore returns the offline store, loading it on first use
+func localStore() *offlineStore {
+	storeOnce.Do(func() {
+		store = &offlineStore{path: storePath()}
+		if data, err := os.ReadFile(store.path); err == nil {
+			json.Unmarshal(data, store)
+		}
+		store.mirror()
+	})
+	return store
+}
+
+func storePath() string {
+	if path := os.Getenv("LIBRARY_CLI_STORE"); path != "" {
+		return path
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "library-api-cli", "store.json")
+}
+
+func (s *offlineStore) mirror() {
+	s.queued.Store(int64(len(s.Queue)))
+	s.conflicts.Store(int64(len(s.Conflicts)))
+	s.syncedAt.Store(s.SyncedAt.Unix())
+}
+
+// save writes the store atomically; callers hold s.mu
+func (s *offlineStore) save() error {
+	s.mirror()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// pendingNotice describes queued writes and unresolved conflicts, or "" when there are none
+func pendingNotice() string {
+	s := localStore()
+
+	var parts []string
+	if n := s.queued.Load(); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d change(s) queued until the server is reachable", n))
+	}
+	if n := s.conflicts.Load(); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d offline change(s) conflicted, see `conflicts`", n))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// cacheAge describes when the offline cache was last refreshed
+func cacheAge() string {
+	synced := localStore().syncedAt.Load()
+	if synced <= 0 {
+		return "never synced"
+	}
+	return "cached " + time.Unix(synced, 0).Format("Jan 2 15:04")
+}
+
+// listBooks returns the catalogue, from the cache when the server is unreachable
+func listBooks(ctx context.Context) ([]models.Book, bool, error) {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replay(ctx)
+	books, err := api.ListBooks(ctx)
+	if errors.Is(err, client.ErrUnavailable) {
+		return append([]models.Book(nil), s.Books...), true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.refresh(books)
+	return books, false, nil
+}
+
+// searchBooks searches on the server, or in the cache with the same matching rules when offline
+func searchBooks(ctx context.Context, query string) ([]models.Book, bool, error) {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replay(ctx)
+	books, err := api.SearchBooks(ctx, query)
+	if !errors.Is(err, client.ErrUnavailable) {
+		return books, false, err
+	}
+
+	q := strings.ToLower(strings.TrimSpace(query))
+	var matches []models.Book
+	for _, b := range s.Books {
+		if strings.Contains(strings.ToLower(b.BookName), q) ||
+			strings.Contains(strings.ToLower(b.Author), q) ||
+			strconv.Itoa(b.ISBN) == q ||
+			b.Year > 0 && strconv.Itoa(b.Year) == q {
+			matches = append(matches, b)
+		}
+	}
+	return matches, true, nil
+}
+
+// getBook fetches a single book, from the cache when offline
+func getBook(ctx context.Context, id int64) (models.Book, bool, error) {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, err := api.GetBook(ctx, id)
+	if !errors.Is(err, client.ErrUnavailable) {
+		return book, false, err
+	}
+	for _, b := range s.Books {
+		if b.ID == id {
+			return b, true, nil
+		}
+	}
+	return models.Book{}, true, &client.APIError{StatusCode: 404, Message: "Book not found in offline cache"}
+}
+
+// addBook creates a book, queueing it when the server is unreachable
+func addBook(ctx context.Context, book models.Book) (bool, error) {
+	return localStore().write(ctx, queuedOp{Kind: opAdd, Book: book, IdempotencyKey: client.NewIdempotencyKey()})
+}
+
+// editBook changes a book field, queueing the edit when the server is unreachable
+func editBook(ctx context.Context, edit models.EditRequest) (bool, error) {
+	return localStore().write(ctx, queuedOp{Kind: opEdit, Edit: edit, Title: edit.Title})
+}
+
+// deleteBook deletes books by title, queueing the delete when the server is unreachable
+func deleteBook(ctx context.Context, title string) (bool, error) {
+	return localStore().write(ctx, queuedOp{Kind: opDelete, Title: title})
+}
+
+// deleteBookByID deletes one book, queueing the delete when the server is unreachable
+func deleteBookByID(ctx context.Context, book models.Book) (bool, error) {
+	return localStore().write(ctx, queuedOp{Kind: opDeleteID, Book: book})
+}
+
+// updateBook changes the fields set in update on one book, queueing the
+// update when the server is unreachable
+func updateBook(ctx context.Context, book models.Book, update models.UpdateBookRequest) (bool, error) {
+	return localStore().write(ctx, queuedOp{Kind: opUpdate, Book: book, Update: update})
+}
+
+// syncOffline replays the queue now and reports what happened
+func syncOffline(ctx context.Context) (syncReport, error) {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, err := s.replay(ctx)
+	if err == nil {
+		if books, listErr := api.ListBooks(ctx); listErr == nil {
+			s.refresh(books)
+		}
+	}
+	return report, err
+}
+
+// write sends op to the server, or applies it to the cache and queues it if
+// the server can't be reached. It reports whether the op was queued.
+func (s *offlineStore) write(ctx context.Context, op queuedOp) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// replay first so queued writes keep their order relative to this one
+	if _, err := s.replay(ctx); errors.Is(err, client.ErrUnavailable) {
+		return true, s.enqueue(op)
+	}
+
+	err := s.send(ctx, op)
+	if errors.Is(err, client.ErrUnavailable) {
+		return true, s.enqueue(op)
+	}
+	return false, err
+}
+
+// createRequest is the add request that recreates book on the server
+func createRequest(book models.Book) models.CreateBookRequest {
+	return models.CreateBookRequest{BookName: book.BookName, Author: book.Author, ISBN: book.ISBN, Year: book.Year}
+}
+
+// send performs op against the server
+func (s *offlineStore) send(ctx context.Context, op queuedOp) error {
+	switch op.Kind {
+	case opAdd:
+		if op.IdempotencyKey != "" {
+			ctx = client.WithIdempotencyKey(ctx, op.IdempotencyKey)
+		}
+		return api.AddBook(ctx, createRequest(op.Book))
+	case opEdit:
+		return api.EditBook(ctx, op.Edit)
+	case opDelete:
+		return api.DeleteBook(ctx, op.Title)
+	case opDeleteID:
+		return api.DeleteBookByID(ctx, op.Book.ID)
+	case opUpdate:
+		_, err := api.UpdateBook(ctx, op.Book.ID, op.Update)
+		return err
+	}
+	return fmt.Errorf("unknown queued operation %q", op.Kind)
+}
+
+// enqueue records the records op expects to change, applies it to the cache and queues it
+func (s *offlineStore) enqueue(op queuedOp) error {
+	op.QueuedAt = time.Now()
+
+	switch op.Kind {
+	case opAdd:
+		// offline-added books get negative ids until the server assigns real ones
+		s.NextTempID--
+		op.Book.ID = s.NextTempID
+		s.Books = append(s.Books, op.Book)
+	case opEdit, opDelete:
+		op.Base = s.booksTitled(op.Title)
+		s.Books = applyLocally(s.Books, op)
+	case opUpdate:
+		if op.Book.ID < 0 {
+			// a book that only exists in the queue is updated in its add
+			s.updateQueuedAdd(op.Book.ID, op.Update)
+			s.Books = applyLocally(s.Books, op)
+			return s.save()
+		}
+		for _, b := range s.Books {
+			if b.ID == op.Book.ID {
+				op.Base = []models.Book{b}
+			}
+		}
+		s.Books = applyLocally(s.Books, op)
+	case opDeleteID:
+		if op.Book.ID < 0 {
+			// deleting a book that only exists in the queue cancels its add
+			s.dropQueuedAdd(op.Book.ID)
+			s.Books = applyLocally(s.Books, op)
+			return s.save()
+		}
+		for _, b := range s.Books {
+			if b.ID == op.Book.ID {
+				op.Base = []models.Book{b}
+			}
+		}
+		s.Books = applyLocally(s.Books, op)
+	}
+
+	s.Queue = append(s.Queue, op)
+	return s.save()
+}
+
+func (s *offlineStore) dropQueuedAdd(tempID int64) {
+	for i, op := range s.Queue {
+		if op.Kind == opAdd && op.Book.ID == tempID {
+			s.Queue = append(s.Queue[:i], s.Queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *offlineStore) updateQueuedAdd(tempID int64, update models.UpdateBookRequest) {
+	for i, op := range s.Queue {
+		if op.Kind == opAdd && op.Book.ID == tempID {
+			s.Queue[i].Book = applyUpdate(op.Book, update)
+			return
+		}
+	}
+}
+
+// replay sends queued writes in order, stopping if the server becomes
+// unreachable. Writes whose target records changed on the server since they
+// were queued, or that the server rejects, are moved to the conflict list.
+func (s *offlineStore) replay(ctx context.Context) (report syncReport, err error) {
+	if len(s.Queue) == 0 {
+		return report, nil
+	}
+	defer func() {
+		report.Pending = len(s.Queue)
+		s.save()
+	}()
+
+	addConflict := func(c conflict) {
+		report.Conflicts = append(report.Conflicts, c)
+		s.Conflicts = append(s.Conflicts, c)
+	}
+
+	for len(s.Queue) > 0 {
+		op := s.Queue[0]
+
+		current, err := s.serverState(ctx, op)
+		if errors.Is(err, client.ErrUnavailable) {
+			return report, err
+		}
+		if err == nil && op.Kind == opDeleteID && len(current) == 0 {
+			// already gone, nothing to do
+			s.Queue = s.Queue[1:]
+			report.Applied++
+			continue
+		}
+		if err == nil && op.Kind != opAdd && !sameBooks(current, op.Base) {
+			addConflict(conflict{
+				Op:     op,
+				Reason: "record changed on the server since the change was queued",
+				Server: current,
+			})
+			s.Queue = s.Queue[1:]
+			continue
+		}
+
+		if err == nil {
+			err = s.send(ctx, op)
+		}
+		if errors.Is(err, client.ErrUnavailable) {
+			return report, err
+		}
+		if err != nil {
+			addConflict(conflict{Op: op, Reason: err.Error(), Server: current})
+		} else {
+			report.Applied++
+		}
+		s.Queue = s.Queue[1:]
+		if err == nil {
+			s.changedByReplay(current)
+		}
+	}
+	return report, nil
+}
+
+// changedByReplay forgets the update times the queued writes expect for
+// books a replayed write just changed. The new times come from our own
+// change, so those books are matched on their fields from now on.
+func (s *offlineStore) changedByReplay(books []models.Book) {
+	for _, changed := range books {
+		for i := range s.Queue {
+			for j := range s.Queue[i].Base {
+				if s.Queue[i].Base[j].ID == changed.ID {
+					s.Queue[i].Base[j].UpdatedAt = time.Time{}
+				}
+			}
+		}
+	}
+}
+
+// serverState fetches the records a queued op targets as they are on the server now
+func (s *offlineStore) serverState(ctx context.Context, op queuedOp) ([]models.Book, error) {
+	switch op.Kind {
+	case opEdit, opDelete:
+		books, err := api.SearchBooks(ctx, op.Title)
+		if err != nil {
+			return nil, err
+		}
+		var matches []models.Book
+		for _, b := range books {
+			if b.BookName == op.Title {
+				matches = append(matches, b)
+			}
+		}
+		return matches, nil
+	case opDeleteID, opUpdate:
+		book, err := api.GetBook(ctx, op.Book.ID)
+		if errors.Is(err, client.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.Book{book}, nil
+	}
+	return nil, nil
+}
+
+// refresh replaces the cached catalogue with a fresh copy from the server
+// and re-applies writes that are still queued on top of it
+func (s *offlineStore) refresh(books []models.Book) {
+	s.Books = append([]models.Book(nil), books...)
+	for _, op := range s.Queue {
+		if op.Kind == opAdd {
+			s.Books = append(s.Books, op.Book)
+			continue
+		}
+		s.Books = applyLocally(s.Books, op)
+	}
+	s.SyncedAt = time.Now()
+	s.save()
+}
+
+func (s *offlineStore) booksTitled(title string) []models.Book {
+	var matches []models.Book
+	for _, b := range s.Books {
+		if b.BookName == title {
+			matches = append(matches, b)
+		}
+	}
+	return matches
+}
+
+// clearConflicts forgets the recorded conflicts once the user has dealt with them
+func clearConflicts() error {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Conflicts = nil
+	return s.save()
+}
+
+// listConflicts returns the recorded conflicts
+func listConflicts() []conflict {
+	s := localStore()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]conflict(nil), s.Conflicts...)
+}
+
+// applyLocally mirrors an edit or delete onto the cached books
+func applyLocally(books []models.Book, op queuedOp) []models.Book {
+	out := books[:0]
+	for _, b := range books {
+		switch {
+		case op.Kind == opDelete && b.BookName == op.Title:
+			continue
+		case op.Kind == opDeleteID && b.ID == op.Book.ID:
+			continue
+		case op.Kind == opUpdate && b.ID == op.Book.ID:
+			b = applyUpdate(b, op.Update)
+		case op.Kind == opEdit && b.BookName == op.Title:
+			switch op.Edit.Field {
+			case "Book_name":
+				b.BookName = op.Edit.Value
+			case "Author":
+				b.Author = op.Edit.Value
+			case "ISBN":
+				if v, err := strconv.Atoi(op.Edit.Value); err == nil {
+					b.ISBN = v
+				}
+			case "Year":
+				if v, err := strconv.Atoi(op.Edit.Value); err == nil {
+					b.Year = v
+				}
+			}
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// applyUpdate returns b with the fields set in update changed
+func applyUpdate(b models.Book, update models.UpdateBookRequest) models.Book {
+	if update.BookName != nil {
+		b.BookName = *update.BookName
+	}
+	if update.Author != nil {
+		b.Author = *update.Author
+	}
+	if update.ISBN != nil {
+		b.ISBN = *update.ISBN
+	}
+	if update.Year != nil {
+		b.Year = *update.Year
+	}
+	return b
+}
+
+// sameBooks reports whether the server records match the ones a queued op
+// expected. A book matches when it has the same id and hasn't been updated
+// since. Books added offline only have a temporary id, and books changed by
+// an earlier replayed write have no update time to expect, so those are
+// matched on their fields.
+func sameBooks(server, base []models.Book) bool {
+	if len(server) != len(base) {
+		return false
+	}
+	used := make([]bool, len(server))
+	for _, want := range base {
+		found := false
+		for i, got := range server {
+			if used[i] || want.ID > 0 && got.ID != want.ID {
+				continue
+			}
+			if want.ID > 0 && !want.UpdatedAt.IsZero() {
+				if !got.UpdatedAt.Equal(want.UpdatedAt) {
+					continue
+				}
+			} else if got.BookName != want.BookName || got.Author != want.Author || got.ISBN != want.ISBN || got.Year != want.Year {
+				continue
+			}
+			used[i], found = true, true
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kushalpraja/library-api/client"
+	"github.com/kushalpraja/library-api/models"
+)
+
+// stubLibrary is an in-memory library API serving the routes the CLI
+// uses. While down it drops every connection, like an unreachable server.
+type stubLibrary struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	down   bool
+	books  []models.Book
+	nextID int64
+	// clock stamps updated_at, a second later on every write
+	clock time.Time
+}
+
+func newStubLibrary(t *testing.T, books ...models.Book) *stubLibrary {
+	t.Helper()
+	s := &stubLibrary{clock: time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)}
+	for _, b := range books {
+		s.add(b)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/books/list", func(w http.ResponseWriter, r *http.Request) {
+		s.reply(w, http.StatusOK, s.books)
+	})
+	mux.HandleFunc("GET /v1/books/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		matches := []models.Book{}
+		for _, b := range s.books {
+			if strings.Contains(strings.ToLower(b.BookName), strings.ToLower(q)) ||
+				strings.Contains(strings.ToLower(b.Author), strings.ToLower(q)) ||
+				strconv.Itoa(b.ISBN) == q || b.Year > 0 && strconv.Itoa(b.Year) == q {
+				matches = append(matches, b)
+			}
+		}
+		s.reply(w, http.StatusOK, matches)
+	})
+	mux.HandleFunc("GET /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if i := s.find(r); i >= 0 {
+			s.reply(w, http.StatusOK, s.books[i])
+			return
+		}
+		s.reply(w, http.StatusNotFound, models.ErrorResponse{Error: "Book not found"})
+	})
+	mux.HandleFunc("POST /v1/books/add", func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateBookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.add(models.Book{BookName: req.BookName, Author: req.Author, ISBN: req.ISBN, Year: req.Year})
+		s.reply(w, http.StatusCreated, map[string]string{"message": "Book added successfully"})
+	})
+	mux.HandleFunc("PATCH /v1/books/edit", func(w http.ResponseWriter, r *http.Request) {
+		var req models.EditRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		found := false
+		for i, b := range s.books {
+			if b.BookName != req.Title {
+				continue
+			}
+			found = true
+			switch req.Field {
+			case "Book_name":
+				b.BookName = req.Value
+			case "Author":
+				b.Author = req.Value
+			case "ISBN":
+				b.ISBN, _ = strconv.Atoi(req.Value)
+			case "Year":
+				b.Year, _ = strconv.Atoi(req.Value)
+			}
+			s.books[i] = s.stamp(b)
+		}
+		if !found {
+			s.reply(w, http.StatusNotFound, models.ErrorResponse{Error: "Book not found"})
+			return
+		}
+		s.reply(w, http.StatusOK, map[string]string{"message": "Book updated successfully"})
+	})
+	mux.HandleFunc("PATCH /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		i := s.find(r)
+		if i < 0 {
+			s.reply(w, http.StatusNotFound, models.ErrorResponse{Error: "Book not found"})
+			return
+		}
+		var req models.UpdateBookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.books[i] = s.stamp(applyUpdate(s.books[i], req))
+		s.reply(w, http.StatusOK, s.books[i])
+	})
+	mux.HandleFunc("DELETE /v1/books/delete", func(w http.ResponseWriter, r *http.Request) {
+		var req models.DeleteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		n := len(s.books)
+		s.books = slices.DeleteFunc(s.books, func(b models.Book) bool { return b.BookName == req.Title })
+		if len(s.books) == n {
+			s.reply(w, http.StatusNotFound, models.ErrorResponse{Error: "Book not found"})
+			return
+		}
+		s.reply(w, http.StatusOK, map[string]string{"message": "Book deleted successfully"})
+	})
+	mux.HandleFunc("DELETE /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		i := s.find(r)
+		if i < 0 {
+			s.reply(w, http.StatusNotFound, models.ErrorResponse{Error: "Book not found"})
+			return
+		}
+		s.books = slices.Delete(s.books, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	prevAPI := api
+	api = client.New(s.URL, client.WithRetries(0, 0))
+	t.Cleanup(func() { api = prevAPI })
+	return s
+}
+
+// add stores b with the next id; callers outside a request hold no lock
+func (s *stubLibrary) add(b models.Book) {
+	s.nextID++
+	b.ID = s.nextID
+	b.CreatedAt = s.clock
+	s.books = append(s.books, s.stamp(b))
+}
+
+func (s *stubLibrary) stamp(b models.Book) models.Book {
+	s.clock = s.clock.Add(time.Second)
+	b.UpdatedAt = s.clock
+	return b
+}
+
+func (s *stubLibrary) find(r *http.Request) int {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return slices.IndexFunc(s.books, func(b models.Book) bool { return b.ID == id })
+}
+
+func (s *stubLibrary) reply(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *stubLibrary) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// edit changes a book on the server behind the CLI's back
+func (s *stubLibrary) edit(id int64, change func(*models.Book)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.books, func(b models.Book) bool { return b.ID == id })
+	change(&s.books[i])
+	s.books[i] = s.stamp(s.books[i])
+}
+
+func (s *stubLibrary) snapshot() []models.Book {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.books)
+}
+
+// useStore points the offline store at an empty file for the length of t
+func useStore(t *testing.T) *offlineStore {
+	t.Helper()
+	storeOnce.Do(func() {})
+	prev := store
+	store = &offlineStore{path: filepath.Join(t.TempDir(), "store.json")}
+	t.Cleanup(func() { store = prev })
+	return store
+}
+
+var (
+	dune = models.Book{BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}
+	emma = models.Book{BookName: "Emma", Author: "Jane Austen", ISBN: 9780141439587}
+)
+
+// goOffline caches the catalogue and takes the server down
+func goOffline(t *testing.T, srv *stubLibrary) {
+	t.Helper()
+	if _, offline, err := listBooks(context.Background()); err != nil || offline {
+		t.Fatalf("caching the catalogue: offline %v, error %v", offline, err)
+	}
+	srv.setDown(true)
+}
+
+// mustQueue returns a check that a write was queued, to wrap its call in
+func mustQueue(t *testing.T) func(queued bool, err error) {
+	return func(queued bool, err error) {
+		t.Helper()
+		if err != nil || !queued {
+			t.Fatalf("write not queued: queued %v, error %v", queued, err)
+		}
+	}
+}
+
+func TestReplayAppliesQueuedWrites(t *testing.T) {
+	srv := newStubLibrary(t, dune)
+	s := useStore(t)
+	ctx := context.Background()
+	goOffline(t, srv)
+
+	year := 1965
+	mustQueue(t)(updateBook(ctx, s.Books[0], models.UpdateBookRequest{Year: &year}))
+	// a second change to the same book expects the first one's result
+	mustQueue(t)(editBook(ctx, models.EditRequest{Title: "Dune", Field: "Author", Value: "F. Herbert"}))
+	mustQueue(t)(addBook(ctx, emma))
+	// an update to a book only added offline is folded into its add
+	mustQueue(t)(updateBook(ctx, s.Books[1], models.UpdateBookRequest{Year: &[]int{1815}[0]}))
+	if len(s.Queue) != 3 {
+		t.Fatalf("queued %d writes, want 3", len(s.Queue))
+	}
+
+	// the cache shows the queued writes
+	books, offline, err := searchBooks(ctx, "1965")
+	if err != nil || !offline || len(books) != 1 || books[0].Author != "F. Herbert" {
+		t.Fatalf("offline search by year: %+v, offline %v, error %v", books, offline, err)
+	}
+
+	srv.setDown(false)
+	report, err := syncOffline(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Applied != 3 || len(report.Conflicts) != 0 || report.Pending != 0 {
+		t.Fatalf("report %+v, want 3 applied", report)
+	}
+	got := srv.snapshot()
+	if len(got) != 2 || got[0].Author != "F. Herbert" || got[0].Year != 1965 || got[1].BookName != "Emma" || got[1].Year != 1815 {
+		t.Errorf("server has %+v", got)
+	}
+	if len(s.Books) != 2 || s.Books[1].ID != 2 {
+		t.Errorf("cache not refreshed after sync: %+v", s.Books)
+	}
+}
+
+func TestReplayConflicts(t *testing.T) {
+	author := "Someone Else"
+	for _, tc := range []struct {
+		name   string
+		queue  func(ctx context.Context, cached models.Book) (bool, error)
+		change func(*models.Book)
+	}{
+		{
+			name: "update after the year changed",
+			queue: func(ctx context.Context, cached models.Book) (bool, error) {
+				return updateBook(ctx, cached, models.UpdateBookRequest{Author: &author})
+			},
+			change: func(b *models.Book) { b.Year = 1965 },
+		},
+		{
+			name: "edit after the year changed",
+			queue: func(ctx context.Context, cached models.Book) (bool, error) {
+				return editBook(ctx, models.EditRequest{Title: "Dune", Field: "Author", Value: author})
+			},
+			change: func(b *models.Book) { b.Year = 1965 },
+		},
+		{
+			name: "delete after an update that kept the fields",
+			queue: func(ctx context.Context, cached models.Book) (bool, error) {
+				return deleteBookByID(ctx, cached)
+			},
+			change: func(*models.Book) {},
+		},
+		{
+			name: "delete by title after a rename",
+			queue: func(ctx context.Context, cached models.Book) (bool, error) {
+				return deleteBook(ctx, "Dune")
+			},
+			change: func(b *models.Book) { b.BookName = "Dune Messiah" },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newStubLibrary(t, dune)
+			s := useStore(t)
+			ctx := context.Background()
+			goOffline(t, srv)
+
+			mustQueue(t)(tc.queue(ctx, s.Books[0]))
+			srv.edit(1, tc.change)
+			want := srv.snapshot()
+
+			srv.setDown(false)
+			report, err := syncOffline(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Applied != 0 || len(report.Conflicts) != 1 {
+				t.Fatalf("report %+v, want one conflict", report)
+			}
+			c := report.Conflicts[0]
+			if c.Reason != "record changed on the server since the change was queued" {
+				t.Errorf("conflict %+v", c)
+			}
+			if got := srv.snapshot(); !slices.Equal(got, want) {
+				t.Errorf("server changed to %+v", got)
+			}
+			if len(listConflicts()) != 1 {
+				t.Errorf("conflict not recorded")
+			}
+		})
+	}
+}
+
+func TestReplayDeleteOfDeletedBook(t *testing.T) {
+	srv := newStubLibrary(t, dune, emma)
+	s := useStore(t)
+	ctx := context.Background()
+	goOffline(t, srv)
+
+	mustQueue(t)(deleteBookByID(ctx, s.Books[0]))
+	srv.mu.Lock()
+	srv.books = srv.books[1:]
+	srv.mu.Unlock()
+
+	srv.setDown(false)
+	report, err := syncOffline(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Applied != 1 || len(report.Conflicts) != 0 {
+		t.Fatalf("report %+v, want the delete applied", report)
+	}
+}
+
+func TestReplayStopsWhileUnreachable(t *testing.T) {
+	srv := newStubLibrary(t, dune)
+	s := useStore(t)
+	ctx := context.Background()
+	goOffline(t, srv)
+
+	mustQueue(t)(addBook(ctx, emma))
+	report, err := syncOffline(ctx)
+	if !errors.Is(err, client.ErrUnavailable) || report.Pending != 1 {
+		t.Fatalf("sync while down: report %+v, error %v", report, err)
+	}
+	if len(s.Queue) != 1 || len(srv.snapshot()) != 1 {
+		t.Errorf("queue %+v, server %+v", s.Queue, srv.snapshot())
+	}
+
+	// the queue is kept on disk
+	var saved offlineStore
+	data, _ := os.ReadFile(s.path)
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved.Queue) != 1 || saved.Queue[0].IdempotencyKey == "" {
+		t.Errorf("saved queue %+v, error %v", saved.Queue, err)
+	}
+}
+
+func TestSameBooks(t *testing.T) {
+	at := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	stored := models.Book{ID: 1, BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719, UpdatedAt: at}
+	updated := stored
+	updated.UpdatedAt = at.Add(time.Second)
+	reyeared := stored
+	reyeared.Year = 1965
+
+	for _, tc := range []struct {
+		name         string
+		server, base []models.Book
+		want         bool
+	}{
+		{"unchanged", []models.Book{stored}, []models.Book{stored}, true},
+		{"updated since", []models.Book{updated}, []models.Book{stored}, false},
+		{"another book", []models.Book{{ID: 2, UpdatedAt: at}}, []models.Book{stored}, false},
+		{"gone", nil, []models.Book{stored}, false},
+		{"new book with the title", []models.Book{stored, {ID: 2}}, []models.Book{stored}, false},
+		{"added offline", []models.Book{stored}, []models.Book{{ID: -1, BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}}, true},
+		{"added offline, year differs", []models.Book{reyeared}, []models.Book{{ID: -1, BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}}, false},
+		{"changed by replay", []models.Book{updated}, []models.Book{{ID: 1, BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}}, true},
+		{"changed by replay and since", []models.Book{reyeared}, []models.Book{{ID: 1, BookName: "Dune", Author: "Frank Herbert", ISBN: 9780441172719}}, false},
+	} {
+		if got := sameBooks(tc.server, tc.base); got != tc.want {
+			t.Errorf("%s: sameBooks = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestApplyLocallyEditsYear(t *testing.T) {
+	books := applyLocally([]models.Book{dune, emma}, queuedOp{Kind: opEdit, Title: "Dune", Edit: models.EditRequest{Title: "Dune", Field: "Year", Value: "1965"}})
+	if books[0].Year != 1965 || books[1].Year != 0 {
+		t.Errorf("after the edit: %+v", books)
+	}
+}
+
+func TestConflictTarget(t *testing.T) {
+	name, year := "Dune Messiah", 1969
+	for _, tc := range []struct {
+		op   queuedOp
+		want string
+	}{
+		{queuedOp{Kind: opAdd, Book: dune}, "Dune"},
+		{queuedOp{Kind: opEdit, Title: "Dune", Edit: models.EditRequest{Field: "Year", Value: "1965"}}, "Dune: Year=1965"},
+		{queuedOp{Kind: opDelete, Title: "Dune"}, "Dune"},
+		{queuedOp{Kind: opDeleteID, Book: models.Book{ID: 3, BookName: "Dune"}}, "Dune (#3)"},
+		{queuedOp{Kind: opUpdate, Book: models.Book{ID: 3, BookName: "Dune"}, Update: models.UpdateBookRequest{BookName: &name, Year: &year}},
+			"Dune (#3): book_name=Dune Messiah, year=1969"},
+	} {
+		if got := conflictTarget(tc.op); got != tc.want {
+			t.Errorf("conflictTarget(%s) = %q, want %q", tc.op.Kind, got, tc.want)
+		}
+	}
+}
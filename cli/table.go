@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kushalpraja/library-api/models"
+)
+
+// Columns of the book table, in display order
+const (
+	colID = iota
+	colTitle
+	colAuthor
+	colISBN
+)
+
+var bookColumns = []table.Column{
+	{Title: "  ID", Width: 8},
+	{Title: "Title", Width: 34},
+	{Title: "Author", Width: 26},
+	{Title: "ISBN", Width: 14},
+}
+
+// detailStyle frames the selected book, set by applyTheme
+var detailStyle lipgloss.Style
+
+// booksMsg carries the result of a list request; offline is set when the
+// books came from the local cache because the server was unreachable
+type booksMsg struct {
+	books   []models.Book
+	offline bool
+}
+
+// bookList is the state of the "List Books" table view
+type bookList struct {
+	books    []models.Book // everything returned by the server
+	visible  []models.Book // books matching the filter, in sort order
+	table    table.Model
+	filter   textinput.Model
+	sortCol  int
+	sortDesc bool
+	marked   map[int64]bool // rows selected for a bulk action
+	offline  bool
+}
+
+func newBookList(books []models.Book) bookList {
+	// scroll with the configured keys, leaving d, u, f, b and space free
+	// for the list's own actions
+	tableKeys := table.DefaultKeyMap()
+	tableKeys.LineUp = keys.Up
+	tableKeys.LineDown = keys.Down
+	tableKeys.PageUp = keys.PageUp
+	tableKeys.PageDown = keys.PageDown
+	tableKeys.HalfPageUp = key.NewBinding(key.WithDisabled())
+	tableKeys.HalfPageDown = key.NewBinding(key.WithDisabled())
+	tableKeys.GotoTop = keys.Top
+	tableKeys.GotoBottom = keys.Bottom
+
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true)
+	styles.Selected = selectedStyle
+
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter by title, author or ISBN"
+	filter.CharLimit = 100
+	filter.Width = 40
+
+	l := bookList{
+		books: books,
+		table: table.New(
+			table.WithFocused(true),
+			table.WithHeight(15),
+			table.WithKeyMap(tableKeys),
+			table.WithStyles(styles),
+		),
+		filter:  filter,
+		sortCol: colID,
+		marked:  make(map[int64]bool),
+	}
+	l.refresh()
+	return l
+}
+
+// refresh re-applies the filter and sort order and rebuilds the table rows
+func (l *bookList) refresh() {
+	query := strings.ToLower(strings.TrimSpace(l.filter.Value()))
+	l.visible = l.visible[:0]
+	for _, b := range l.books {
+		if query == "" ||
+			strings.Contains(strings.ToLower(b.BookName), query) ||
+			strings.Contains(strings.ToLower(b.Author), query) ||
+			strings.Contains(strconv.Itoa(b.ISBN), query) {
+			l.visible = append(l.visible, b)
+		}
+	}
+
+	sort.SliceStable(l.visible, func(i, j int) bool {
+		if l.sortDesc {
+			return bookLess(l.visible[j], l.visible[i], l.sortCol)
+		}
+		return bookLess(l.visible[i], l.visible[j], l.sortCol)
+	})
+
+	columns := make([]table.Column, len(bookColumns))
+	copy(columns, bookColumns)
+	arrow := " ▲"
+	if l.sortDesc {
+		arrow = " ▼"
+	}
+	columns[l.sortCol].Title += arrow
+
+	rows := make([]table.Row, len(l.visible))
+	for i, b := range l.visible {
+		mark := "  "
+		if l.marked[b.ID] {
+			mark = "✓ "
+		}
+		rows[i] = table.Row{mark + strconv.FormatInt(b.ID, 10), b.BookName, b.Author, strconv.Itoa(b.ISBN)}
+	}
+	l.table.SetColumns(columns)
+	l.table.SetRows(rows)
+	if l.table.Cursor() >= len(rows) {
+		l.table.SetCursor(max(len(rows)-1, 0))
+	}
+}
+
+func bookLess(a, b models.Book, col int) bool {
+	switch col {
+	case colTitle:
+		return strings.ToLower(a.BookName) < strings.ToLower(b.BookName)
+	case colAuthor:
+		return strings.ToLower(a.Author) < strings.ToLower(b.Author)
+	case colISBN:
+		return a.ISBN < b.ISBN
+	}
+	return a.ID < b.ID
+}
+
+// markedBooks returns the books selected with space, or the book under
+// the cursor when nothing is marked
+func (l bookList) markedBooks() []models.Book {
+	var books []models.Book
+	for _, b := range l.visible {
+		if l.marked[b.ID] {
+			books = append(books, b)
+		}
+	}
+	if len(books) == 0 {
+		if book, ok := l.selected(); ok {
+			books = append(books, book)
+		}
+	}
+	return books
+}
+
+// selected returns the book under the cursor
+func (l bookList) selected() (models.Book, bool) {
+	i := l.table.Cursor()
+	if i < 0 || i >= len(l.visible) {
+		return models.Book{}, false
+	}
+	return l.visible[i], true
+}
+
+func (m model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	// while the filter is focused keystrokes edit it and narrow the table as you type
+	if m.list.filter.Focused() {
+		typed := msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace
+		switch {
+		case typed:
+			m.list.filter, cmd = m.list.filter.Update(msg)
+		case key.Matches(msg, keys.ForceQuit):
+			return m, tea.Quit
+		case key.Matches(msg, keys.Back):
+			m.list.filter.SetValue("")
+			m.list.filter.Blur()
+		case key.Matches(msg, keys.Select, keys.Up, keys.Down):
+			m.list.filter.Blur()
+		default:
+			m.list.filter, cmd = m.list.filter.Update(msg)
+		}
+		m.list.refresh()
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Back):
+		m.state = StateMenu
+		return m, nil
+	case key.Matches(msg, keys.Help):
+		m.help.ShowAll = !m.help.ShowAll
+		return m, nil
+	case key.Matches(msg, keys.Filter):
+		m.list.filter.Focus()
+		return m, textinput.Blink
+	case key.Matches(msg, keys.Refresh):
+		m.state = StateLoading
+		return m, makeListRequest()
+	case key.Matches(msg, keys.Sort):
+		// pick the sort column, pressing it again reverses the order
+		col := keys.sortColumn(msg.String())
+		if col < 0 || col >= len(bookColumns) {
+			return m, nil
+		}
+		if col == m.list.sortCol {
+			m.list.sortDesc = !m.list.sortDesc
+		} else {
+			m.list.sortCol = col
+			m.list.sortDesc = false
+		}
+		m.list.refresh()
+		return m, nil
+	case key.Matches(msg, keys.Edit):
+		book, ok := m.list.selected()
+		if !ok {
+			return m, nil
+		}
+		m = m.startEdit()
+		m.editTarget = book
+		m.titleInput.SetValue(book.BookName)
+		m.currentInput = 1
+		return m.updateEditInputFocus(), textinput.Blink
+	case key.Matches(msg, keys.Mark):
+		if book, ok := m.list.selected(); ok {
+			m.list.marked[book.ID] = !m.list.marked[book.ID]
+			m.list.refresh()
+		}
+		return m, nil
+	case key.Matches(msg, keys.Delete):
+		books := m.list.markedBooks()
+		if len(books) == 0 {
+			return m, nil
+		}
+		return m.confirmDelete(books), nil
+	}
+
+	m.list.table, cmd = m.list.table.Update(msg)
+	return m, cmd
+}
+
+func (m model) viewList() string {
+	s := titleStyle.Render(fmt.Sprintf("📚 Books (%d of %d)", len(m.list.visible), len(m.list.books))) + "\n\n"
+	if m.list.offline {
+		s += fieldErrorStyle.Render("Server unreachable, showing offline copy ("+cacheAge()+")") + "\n\n"
+	}
+
+	if m.list.filter.Focused() || m.list.filter.Value() != "" {
+		s += m.list.filter.View() + "\n\n"
+	}
+
+	detail := "No book selected"
+	if book, ok := m.list.selected(); ok {
+		detail = lipgloss.NewStyle().Bold(true).Render(book.BookName) + "\n\n" +
+			"Author: " + book.Author + "\n" +
+			"ISBN:   " + strconv.Itoa(book.ISBN) + "\n"
+		if book.Year != 0 {
+			detail += "Year:   " + strconv.Itoa(book.Year) + "\n"
+		}
+		detail += "ID:     " + strconv.FormatInt(book.ID, 10)
+	}
+	s += lipgloss.JoinHorizontal(lipgloss.Top, m.list.table.View(), "  ", detailStyle.Render(detail)) + "\n\n"
+
+	s += m.viewHelp()
+	return s
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// theme is the set of colors the TUI is drawn with
+type theme struct {
+	Primary   string `json:"primary"`    // title background, selection and highlights
+	OnPrimary string `json:"on_primary"` // text drawn on the primary color
+	Success   string `json:"success"`    // responses and valid fields
+	Error     string `json:"error"`      // errors and invalid fields
+	Muted     string `json:"muted"`      // help text
+	Border    string `json:"border"`     // input and panel borders
+}
+
+// builtinThemes can be picked by name in the config file
+var builtinThemes = map[string]theme{
+	"dark": {
+		Primary:   "#7D56F4",
+		OnPrimary: "#FAFAFA",
+		Success:   "#04B575",
+		Error:     "#FF5F87",
+		Muted:     "#626262",
+		Border:    "#4A4A4A",
+	},
+	"light": {
+		Primary:   "#5A3FD1",
+		OnPrimary: "#FFFFFF",
+		Success:   "#007A4D",
+		Error:     "#C8102E",
+		Muted:     "#6E6E6E",
+		Border:    "#B0B0B0",
+	},
+	"high-contrast": {
+		Primary:   "#FFFF00",
+		OnPrimary: "#000000",
+		Success:   "#00FF00",
+		Error:     "#FF0000",
+		Muted:     "#FFFFFF",
+		Border:    "#FFFFFF",
+	},
+}
+
+// tuiConfig is the optional config file read at startup, e.g.
+//
+//	{"theme": "light", "colors": {"primary": "#005FAF"}, "keys": {"quit": ["ctrl+q"]}}
+type tuiConfig struct {
+	Theme  string              `json:"theme"`
+	Colors theme               `json:"colors"`
+	Keys   map[string][]string `json:"keys"`
+}
+
+func init() {
+	applyTheme(builtinThemes["dark"])
+}
+
+func tuiConfigPath() string {
+	if path := os.Getenv("LIBRARY_TUI_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "library-api-cli", "tui.json")
+}
+
+// loadTUIConfig applies the theme and key bindings from the config file.
+// A missing file leaves the dark theme and default keys in place.
+func loadTUIConfig() error {
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	cfg := tuiConfig{Theme: "dark"}
+	path := tuiConfigPath()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			applyTheme(builtinThemes["dark"])
+			return err
+		default:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				applyTheme(builtinThemes["dark"])
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	base, ok := builtinThemes[cfg.Theme]
+	if !ok {
+		applyTheme(builtinThemes["dark"])
+		return fmt.Errorf("unknown theme %q, expected dark, light or high-contrast", cfg.Theme)
+	}
+	applyTheme(mergeTheme(base, cfg.Colors))
+	return keys.override(cfg.Keys)
+}
+
+// mergeTheme overrides the colors of base that are set in custom
+func mergeTheme(base, custom theme) theme {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&base.Primary, custom.Primary)
+	set(&base.OnPrimary, custom.OnPrimary)
+	set(&base.Success, custom.Success)
+	set(&base.Error, custom.Error)
+	set(&base.Muted, custom.Muted)
+	set(&base.Border, custom.Border)
+	return base
+}
+
+// applyTheme rebuilds the shared styles from t
+func applyTheme(t theme) {
+	primary := lipgloss.Color(t.Primary)
+	border := lipgloss.Color(t.Border)
+
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(t.OnPrimary)).
+		Background(primary).
+		Padding(0, 1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primary)
+
+	responseStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(t.Success)).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(border).
+		Padding(1)
+
+	errorStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(t.Error)).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(border).
+		Padding(1)
+
+	inputStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(border).
+		Padding(1)
+
+	detailStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(border).
+		Padding(0, 1).
+		Width(36)
+
+	fieldErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Error))
+	fieldOKStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Success))
+
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color(t.Muted))
+	helpStyles = help.Styles{
+		ShortKey:       muted.Bold(true),
+		ShortDesc:      muted,
+		ShortSeparator: muted.Faint(true),
+		Ellipsis:       muted.Faint(true),
+		FullKey:        muted.Bold(true),
+		FullDesc:       muted,
+		FullSeparator:  muted.Faint(true),
+	}
+}